@@ -0,0 +1,52 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// MaxAuditAccepted is the maximum number of accepted passwords recorded
+// in AuditStats.
+const MaxAuditAccepted = 1000
+
+// AuditStats describes the results of checking a corpus of passwords.
+type AuditStats struct {
+	Total    int            // number of checked passwords
+	Accepted int            // number of accepted passwords
+	Rejected int            // number of rejected passwords
+	Reasons  map[Reason]int // number of rejected passwords by reason
+
+	// AcceptedPasswords contains the first MaxAuditAccepted accepted
+	// passwords.
+	AcceptedPasswords []string
+}
+
+// AuditCorpus checks passwords read from r, one per line, such as a list
+// of common or breached passwords, and returns statistics showing which of
+// them the policy would accept. Empty lines are skipped. OnReject is not
+// called.
+func (p *Policy) AuditCorpus(r io.Reader) (AuditStats, error) {
+	st := AuditStats{Reasons: make(map[Reason]int)}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		pw := strings.TrimSuffix(scanner.Text(), "\r")
+		if pw == "" {
+			continue
+		}
+		st.Total++
+		if err := p.check([]byte(pw), nil, nil); err != nil {
+			st.Rejected++
+			st.Reasons[err.(*Error).Reason()]++
+			continue
+		}
+		st.Accepted++
+		if len(st.AcceptedPasswords) < MaxAuditAccepted {
+			st.AcceptedPasswords = append(st.AcceptedPasswords, pw)
+		}
+	}
+	return st, scanner.Err()
+}
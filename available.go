@@ -0,0 +1,70 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	qcInitOnce sync.Once
+	qcInitErr  error // nil if passwdqc is available
+)
+
+// Available reports whether the passwdqc implementation works in this
+// process. It runs a self-test the first time it is called, comparing the
+// results of passwdqc with those of the Go port on known passwords. If the
+// self-test fails, which can happen if the C library is miscompiled or
+// misconfigured, for example, when linked statically, checks fail with
+// ErrFailed instead of returning wrong results. Callers can use Available
+// at startup to detect this and fall back to another check, such as a
+// binary built with CGO_ENABLED=0, which uses the Go port only.
+//
+// Without cgo, the Go port is also the implementation being tested, so the
+// self-test can't fail and Available always returns true.
+func Available() bool {
+	return qcReady() == nil
+}
+
+// qcReady returns nil if passwdqc is available, or an error wrapping
+// ErrFailed describing why it is not.
+func qcReady() error {
+	qcInitOnce.Do(func() {
+		qcInitErr = qcSelfTest()
+	})
+	return qcInitErr
+}
+
+// selfTestPasswords are the passwords checked by qcSelfTest: weak ones
+// rejected for different reasons and strong ones.
+var selfTestPasswords = []string{
+	"password",
+	"qwerty123",
+	"Jd7#",
+	"correct horse battery staple",
+	"dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU",
+}
+
+// qcSelfTest checks that passwdqc gives the same results as the Go port.
+func qcSelfTest() error {
+	params := (&Policy{
+		Min:             [5]int{Disabled, 24, 11, 8, 7},
+		Max:             40,
+		PassphraseWords: 3,
+		MatchLength:     4,
+		DenySimilar:     true,
+	}).params()
+	for _, pw := range selfTestPasswords {
+		got, want := qcCheck(&params, []byte(pw), nil, nil), checkGo(&params, []byte(pw), nil, nil)
+		if got != want {
+			return &Error{
+				code: ReasonFailed,
+				desc: fmt.Sprintf("passwordcheck: passwdqc is unavailable: self-test returned %q instead of %q", got, want),
+				err:  ErrFailed,
+			}
+		}
+	}
+	return nil
+}
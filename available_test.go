@@ -0,0 +1,37 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAvailable(t *testing.T) {
+	if !Available() {
+		t.Fatalf("passwdqc is unavailable: %v", qcReady())
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	qcReady()
+	saved := qcInitErr
+	t.Cleanup(func() { qcInitErr = saved })
+	qcInitErr = &Error{code: ReasonFailed, desc: "passwordcheck: passwdqc is unavailable: test", err: ErrFailed}
+
+	if Available() {
+		t.Error("expected passwdqc to be unavailable")
+	}
+	if err := Init(); err != qcInitErr {
+		t.Errorf("Init: expected %v, got %v", qcInitErr, err)
+	}
+	pw := []byte("dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU")
+	err := DefaultPolicy.Check(pw, nil, nil)
+	if !errors.Is(err, ErrFailed) || err.Error() != qcInitErr.Error() {
+		t.Errorf("Check: expected %v, got %v", qcInitErr, err)
+	}
+	if errs := DefaultPolicy.CheckAll(pw, nil, nil); len(errs) != 1 || !errors.Is(errs[0], ErrFailed) {
+		t.Errorf("CheckAll: expected ErrFailed, got %v", errs)
+	}
+}
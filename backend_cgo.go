@@ -0,0 +1,143 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+//go:build cgo
+
+package passwordcheck
+
+// #include "passwdqc.h"
+import "C"
+
+// The C implementation of passwdqc is used when cgo is available.
+
+// cParams returns params converted to passwdqc_params_qc_t.
+func cParams(params *qcParams) (cp C.passwdqc_params_qc_t) {
+	for i, v := range params.min {
+		cp.min[i] = C.int(v)
+	}
+	cp.max = C.int(params.max)
+	cp.passphrase_words = C.int(params.passphraseWords)
+	cp.passphrase_min_word_len = C.int(params.passphraseMinWordLen)
+	cp.match_length = C.int(params.matchLength)
+	cp.similar_match_length = C.int(params.similarMatchLength)
+	if params.similarDeny {
+		cp.similar_deny = 1
+	}
+	if params.nonASCIILetters {
+		cp.non_ascii_letters = 1
+	}
+	if params.skipSame {
+		cp.skip_same = 1
+	}
+	for i, c := range params.unifyMap {
+		cp.unify_map[i] = C.uchar(c)
+	}
+	return
+}
+
+// qcResolvedParams returns params as seen by the C implementation, that is,
+// converted to passwdqc_params_qc_t and back.
+func qcResolvedParams(params *qcParams) (resolved qcParams) {
+	cp := cParams(params)
+	for i, v := range cp.min {
+		resolved.min[i] = int32(v)
+	}
+	resolved.max = int32(cp.max)
+	resolved.passphraseWords = int32(cp.passphrase_words)
+	resolved.passphraseMinWordLen = int32(cp.passphrase_min_word_len)
+	resolved.matchLength = int32(cp.match_length)
+	resolved.similarMatchLength = int32(cp.similar_match_length)
+	resolved.similarDeny = cp.similar_deny != 0
+	resolved.nonASCIILetters = cp.non_ascii_letters != 0
+	resolved.skipSame = cp.skip_same != 0
+	for i, c := range cp.unify_map {
+		resolved.unifyMap[i] = byte(c)
+	}
+	return
+}
+
+// qcVersion returns the version of passwdqc the C implementation is based
+// on.
+func qcVersion() string {
+	return C.PASSWDQC_VERSION
+}
+
+// cString returns a C copy of b, or NULL if b is nil. The returned string
+// must be freed with passwdqc_free.
+func cString(b []byte) *C.char {
+	if b == nil {
+		return nil
+	}
+	return C.CString(string(b))
+}
+
+func qcCheck(params *qcParams, newpass, oldpass, name []byte) string {
+	cp := cParams(params)
+	np, op, u := cString(newpass), cString(oldpass), cString(name)
+	defer C.passwdqc_free(np)
+	defer C.passwdqc_free(op)
+	defer C.passwdqc_free(u)
+	reason := C.passwdqc_check(&cp, np, op, u)
+	if reason == nil {
+		return ""
+	}
+	return C.GoString(reason)
+}
+
+func qcCheckAll(params *qcParams, newpass, oldpass, name []byte) uint {
+	cp := cParams(params)
+	np, op, u := cString(newpass), cString(oldpass), cString(name)
+	defer C.passwdqc_free(np)
+	defer C.passwdqc_free(op)
+	defer C.passwdqc_free(u)
+	return uint(C.passwdqc_check_all(&cp, np, op, u))
+}
+
+func qcBasedOn(params *qcParams, newpass, source []byte) bool {
+	cp := cParams(params)
+	np, s := cString(newpass), cString(source)
+	defer C.passwdqc_free(np)
+	defer C.passwdqc_free(s)
+	return C.passwdqc_based_on(&cp, np, s) != 0
+}
+
+func qcEffectiveLength(params *qcParams, newpass, oldpass, name []byte, dictionary bool) int {
+	cp := cParams(params)
+	np, op, u := cString(newpass), cString(oldpass), cString(name)
+	defer C.passwdqc_free(np)
+	defer C.passwdqc_free(op)
+	defer C.passwdqc_free(u)
+	var d C.int
+	if dictionary {
+		d = 1
+	}
+	return int(C.passwdqc_effective_length(&cp, np, op, u, d))
+}
+
+// passwordStats returns passwdqc statistics for the password, counting
+// words at least minWordLen characters long, and counting non-ASCII
+// characters as letters if nonASCIILetters is set.
+func passwordStats(pass []byte, minWordLen int, nonASCIILetters bool) (st qcStats) {
+	if len(pass) == 0 {
+		return
+	}
+	s := C.CString(string(pass))
+	defer C.passwdqc_free(s)
+	var cs C.passwdqc_stats_t
+	var letters C.int
+	if nonASCIILetters {
+		letters = 1
+	}
+	C.passwdqc_stats(s, C.int(minWordLen), letters, &cs)
+	return qcStats{
+		length:   int(cs.length),
+		words:    int(cs.words),
+		chars:    int(cs.chars),
+		digits:   int(cs.digits),
+		lowers:   int(cs.lowers),
+		uppers:   int(cs.uppers),
+		others:   int(cs.others),
+		unknowns: int(cs.unknowns),
+		classes:  int(cs.classes),
+	}
+}
@@ -0,0 +1,100 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+//go:build cgo
+
+package passwordcheck
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestPasswdqcVersion(t *testing.T) {
+	if v := PasswdqcVersion(); v != passwdqcVersion {
+		t.Errorf("PASSWDQC_VERSION %q doesn't match Go port version %q", v, passwdqcVersion)
+	}
+}
+
+// TestGoPortMatchesC checks that the Go port of passwdqc gives the same
+// results as the C implementation.
+func TestGoPortMatchesC(t *testing.T) {
+	words, err := LoadWordlistGzip("testdata/passwords.txt.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	var passwords [][]byte
+	for i := 0; i < 50; i++ {
+		w := words[rnd.Intn(len(words))]
+		passwords = append(passwords,
+			[]byte(w),
+			[]byte(fmt.Sprintf("%s%d!", w, rnd.Intn(10000))),
+			[]byte(fmt.Sprintf("X%s %s\xc3\xa9", w, words[rnd.Intn(len(words))])),
+		)
+		b := make([]byte, 6+rnd.Intn(20))
+		for j := range b {
+			b[j] = byte(1 + rnd.Intn(255))
+		}
+		passwords = append(passwords, b)
+	}
+	for _, s := range []string{"", "a", "Ncc1701!enterprise", "correct horse battery staple",
+		"2drowssap", "qwerty123", "1qaz2wsx", "P@ssw0rd1984", "abcdefghijklmn", "dw1lIojbTBrq/gii",
+		"Élan vital 42", "naïve café", "Ωmega§ΣΨ", "密码很安全123", "\xc3\x28\xe2\x82"} {
+		passwords = append(passwords, []byte(s))
+	}
+	policies := []*Policy{
+		DefaultPolicy,
+		MustParsePolicy("case=match leet=ignore"),
+		MustParsePolicy("min=8,8,8,8,8 max=8 passphrase=2 match=3"),
+		MustParsePolicy("min=6,10,9,7,6 passphrase=4 wordlen=3 match=5 similar=permit"),
+		MustParsePolicy("min=disabled,disabled,16,disabled,disabled match=0"),
+		MustParsePolicy("nonascii=letters"),
+		MustParsePolicy("similarmatch=3"),
+		MustParsePolicy("match=6 similarmatch=4"),
+		constantTimeSame(DefaultPolicy),
+		constantTimeSame(MustParsePolicy("min=8,8,8,8,8 max=8")),
+	}
+	old, user := []byte("Password2"), []byte("johnsmith")
+	for pi, p := range policies {
+		params := p.params()
+		for i, pass := range passwords {
+			if len(pass) > 0 {
+				if c, g := qcCheck(&params, pass, nil, nil), checkGo(&params, pass, nil, nil); c != g {
+					t.Errorf("%d/%d %q: check: C %q, Go %q", pi, i, pass, c, g)
+				}
+				o := passwords[(i+1)%len(passwords)]
+				if c, g := qcCheck(&params, pass, o, user), checkGo(&params, pass, o, user); c != g {
+					t.Errorf("%d/%d %q, %q: check: C %q, Go %q", pi, i, pass, o, c, g)
+				}
+				if c, g := qcCheckAll(&params, pass, old, user), checkAllGo(&params, pass, old, user); c != g {
+					t.Errorf("%d/%d %q: checkAll: C %#x, Go %#x", pi, i, pass, c, g)
+				}
+				if c, g := qcCheck(&params, pass, pass, user), checkGo(&params, pass, pass, user); c != g {
+					t.Errorf("%d/%d %q: check same: C %q, Go %q", pi, i, pass, c, g)
+				}
+				if c, g := qcCheckAll(&params, pass, pass, user), checkAllGo(&params, pass, pass, user); c != g {
+					t.Errorf("%d/%d %q: checkAll same: C %#x, Go %#x", pi, i, pass, c, g)
+				}
+				if c, g := qcBasedOn(&params, pass, old), basedOnGo(&params, pass, old); c != g {
+					t.Errorf("%d/%d %q: basedOn: C %v, Go %v", pi, i, pass, c, g)
+				}
+				if c, g := qcEffectiveLength(&params, pass, old, user, true), effectiveLengthGo(&params, pass, old, user, true); c != g {
+					t.Errorf("%d/%d %q: effectiveLength: C %d, Go %d", pi, i, pass, c, g)
+				}
+			}
+			mw := int(params.passphraseMinWordLen)
+			if c, g := passwordStats(pass, mw, params.nonASCIILetters), statsGo(string(pass), mw, params.nonASCIILetters); c != g {
+				t.Errorf("%d/%d %q: stats: C %+v, Go %+v", pi, i, pass, c, g)
+			}
+		}
+	}
+}
+
+// constantTimeSame returns a copy of the policy with ConstantTimeSame set.
+func constantTimeSame(p *Policy) *Policy {
+	q := *p
+	q.ConstantTimeSame = true
+	return &q
+}
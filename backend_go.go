@@ -0,0 +1,39 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+//go:build !cgo
+
+package passwordcheck
+
+// The Go port of passwdqc is used when cgo is not available.
+
+func qcVersion() string {
+	return passwdqcVersion
+}
+
+func qcResolvedParams(params *qcParams) qcParams {
+	return *params
+}
+
+func qcCheck(params *qcParams, newpass, oldpass, name []byte) string {
+	return checkGo(params, newpass, oldpass, name)
+}
+
+func qcCheckAll(params *qcParams, newpass, oldpass, name []byte) uint {
+	return checkAllGo(params, newpass, oldpass, name)
+}
+
+func qcBasedOn(params *qcParams, newpass, source []byte) bool {
+	return basedOnGo(params, newpass, source)
+}
+
+func qcEffectiveLength(params *qcParams, newpass, oldpass, name []byte, dictionary bool) int {
+	return effectiveLengthGo(params, newpass, oldpass, name, dictionary)
+}
+
+// passwordStats returns passwdqc statistics for the password, counting
+// words at least minWordLen characters long, and counting non-ASCII
+// characters as letters if nonASCIILetters is set.
+func passwordStats(pass []byte, minWordLen int, nonASCIILetters bool) qcStats {
+	return statsGo(string(pass), minWordLen, nonASCIILetters)
+}
@@ -0,0 +1,294 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// Blocklist is a set of passwords known to be compromised, for example,
+// found in data breaches. Passwords in the blocklist of a policy are
+// rejected with ErrBreached.
+type Blocklist interface {
+	// Contains reports whether the blocklist contains the password.
+	Contains(password []byte) (bool, error)
+}
+
+// BlocklistBloom is a blocklist backed by a Bloom filter, which can hold a
+// large number of passwords using a small amount of memory, about 10 bits
+// per password for a 1% false-positive rate.
+//
+// A Bloom filter never misses passwords added to it, but it may report
+// that it contains a password that was never added (a false positive).
+// Since a match rejects the password, a small fraction of good passwords
+// will be rejected; if this is not acceptable, verify matches against an
+// exact list of passwords.
+//
+// Contains can be called concurrently, but not concurrently with Add or
+// AddFrom.
+type BlocklistBloom struct {
+	bits []uint64
+	m    uint64 // number of bits
+	k    int    // number of hash functions
+}
+
+// NewBlocklistBloom returns a new empty Bloom filter blocklist sized for n
+// passwords with the given false-positive rate, such as 0.01 for 1%.
+func NewBlocklistBloom(n int, falsePositiveRate float64) *BlocklistBloom {
+	if n < 1 {
+		n = 1
+	}
+	if !(falsePositiveRate > 0 && falsePositiveRate < 1) {
+		falsePositiveRate = 0.01
+	}
+	m := math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := int(math.Round(m / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	words := (uint64(m) + 63) / 64
+	return &BlocklistBloom{
+		bits: make([]uint64, words),
+		m:    words * 64,
+		k:    k,
+	}
+}
+
+// hashes returns two hashes of the password used to derive the bit
+// positions.
+func (b *BlocklistBloom) hashes(password []byte) (h1, h2 uint64) {
+	h := fnv.New128a()
+	h.Write(password)
+	var sum [16]byte
+	s := h.Sum(sum[:0])
+	for i := 0; i < 8; i++ {
+		h1 = h1<<8 | uint64(s[i])
+		h2 = h2<<8 | uint64(s[8+i])
+	}
+	return h1, h2 | 1
+}
+
+// Add adds the password to the blocklist.
+func (b *BlocklistBloom) Add(password []byte) {
+	h1, h2 := b.hashes(password)
+	for i := 0; i < b.k; i++ {
+		n := (h1 + uint64(i)*h2) % b.m
+		b.bits[n/64] |= 1 << (n % 64)
+	}
+}
+
+// AddFrom adds passwords read from r, one per line, to the blocklist, and
+// returns the number of added passwords. Empty lines are skipped.
+func (b *BlocklistBloom) AddFrom(r io.Reader) (n int, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if pw := strings.TrimSuffix(scanner.Text(), "\r"); pw != "" {
+			b.Add([]byte(pw))
+			n++
+		}
+	}
+	return n, scanner.Err()
+}
+
+// Contains reports whether the blocklist probably contains the password.
+// It never returns an error.
+func (b *BlocklistBloom) Contains(password []byte) (bool, error) {
+	h1, h2 := b.hashes(password)
+	for i := 0; i < b.k; i++ {
+		n := (h1 + uint64(i)*h2) % b.m
+		if b.bits[n/64]&(1<<(n%64)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// BlocklistFile is a blocklist backed by a sorted file, which is searched
+// with binary search on every call to Contains without loading it into
+// memory, so it can be used with lists too large to fit in memory.
+//
+// The file must contain one entry per line, with lines separated by "\n"
+// or "\r\n", sorted in byte order, for example, with LC_ALL=C sort. If the
+// blocklist is hashed, entries are SHA-1 hashes of passwords in upper-case
+// hexadecimal, optionally followed by a colon and other data, as in the
+// lists of Pwned Passwords ordered by hash; otherwise, entries are
+// passwords.
+//
+// Contains can be called concurrently.
+type BlocklistFile struct {
+	r      io.ReaderAt
+	size   int64
+	hashed bool
+	closer io.Closer
+}
+
+// OpenBlocklistFile opens the named sorted file as a blocklist. If hashed is
+// true, the file must contain SHA-1 hashes of passwords. The file is kept
+// open until Close is called.
+func OpenBlocklistFile(filename string, hashed bool) (*BlocklistFile, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	b := NewBlocklistFile(f, fi.Size(), hashed)
+	b.closer = f
+	return b, nil
+}
+
+// NewBlocklistFile returns a blocklist reading the sorted list of size bytes
+// from r, as described for BlocklistFile.
+func NewBlocklistFile(r io.ReaderAt, size int64, hashed bool) *BlocklistFile {
+	return &BlocklistFile{r: r, size: size, hashed: hashed}
+}
+
+// Close closes the file opened by OpenBlocklistFile. It does nothing for
+// blocklists returned by NewBlocklistFile.
+func (b *BlocklistFile) Close() error {
+	if b.closer == nil {
+		return nil
+	}
+	return b.closer.Close()
+}
+
+// Contains reports whether the blocklist contains the password. It
+// returns an error if the file cannot be read.
+func (b *BlocklistFile) Contains(password []byte) (bool, error) {
+	key := password
+	if b.hashed {
+		sum := sha1.Sum(password)
+		key = []byte(strings.ToUpper(hex.EncodeToString(sum[:])))
+	}
+	// Lines starting before lo are less than the key, and lines starting
+	// at or after hi are greater.
+	lo, hi := int64(0), b.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, err := b.lineStart(lo, mid)
+		if err != nil {
+			return false, err
+		}
+		line, end, err := b.line(start)
+		if err != nil {
+			return false, err
+		}
+		switch c := bytes.Compare(b.entry(line), key); {
+		case c == 0:
+			return true, nil
+		case c < 0:
+			lo = end
+		default:
+			hi = start
+		}
+	}
+	return false, nil
+}
+
+// entry returns the entry of the line to compare with the key.
+func (b *BlocklistFile) entry(line []byte) []byte {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if b.hashed {
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+	}
+	return line
+}
+
+// blocklistChunk is the size of chunks read from blocklist files.
+const blocklistChunk = 512
+
+// lineStart returns the offset of the beginning of the line containing
+// the offset off, which is not before min.
+func (b *BlocklistFile) lineStart(min, off int64) (int64, error) {
+	var buf [blocklistChunk]byte
+	for off > min {
+		n := off - min
+		if n > blocklistChunk {
+			n = blocklistChunk
+		}
+		if _, err := b.r.ReadAt(buf[:n], off-n); err != nil {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			return off - n + int64(i) + 1, nil
+		}
+		off -= n
+	}
+	return min, nil
+}
+
+// line returns the line starting at the offset start without the newline,
+// and the offset of the next line.
+func (b *BlocklistFile) line(start int64) (line []byte, end int64, err error) {
+	var buf [blocklistChunk]byte
+	off := start
+	for off < b.size {
+		n, err := b.r.ReadAt(buf[:], off)
+		if n == 0 && err != nil {
+			return nil, 0, err
+		}
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			line = append(line, buf[:i]...)
+			return line, off + int64(i) + 1, nil
+		}
+		line = append(line, buf[:n]...)
+		off += int64(n)
+	}
+	return line, b.size, nil
+}
+
+// globalTerms are the lower-case terms registered with
+// RegisterGlobalBlocklist.
+var globalTerms []string
+
+// RegisterGlobalBlocklist sets the process-wide list of terms, such as the
+// name of the organization or its products, that passwords must not
+// contain. Unlike DenyPatterns, the terms apply to all policies, including
+// DefaultPolicy, except those with IgnoreGlobalBlocklist set. Passwords
+// containing any of the terms, ignoring case, are rejected with ErrDenied.
+// Empty terms are skipped, and calling it with no terms removes the list.
+//
+// RegisterGlobalBlocklist is not safe to call concurrently with checks:
+// call it during initialization, before serving requests.
+func RegisterGlobalBlocklist(words []string) {
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		if w != "" {
+			terms = append(terms, strings.ToLower(w))
+		}
+	}
+	globalTerms = terms
+}
+
+// containsGlobalTerm reports whether the password contains any of the
+// terms registered with RegisterGlobalBlocklist, ignoring case. Scanning
+// stops when the deadline is exceeded.
+func containsGlobalTerm(password []byte, d *deadline) bool {
+	if len(globalTerms) == 0 {
+		return false
+	}
+	s := string(bytes.ToLower(password))
+	for _, t := range globalTerms {
+		if d.exceeded() {
+			return false
+		}
+		if strings.Contains(s, t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestBlocklistBloom(t *testing.T) {
+	const n = 10000
+	var list strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&list, "breached-%d\r\n", i)
+	}
+	list.WriteString("\n")
+	b := NewBlocklistBloom(n, 0.01)
+	added, err := b.AddFrom(strings.NewReader(list.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != n {
+		t.Fatalf("expected %d passwords, got %d", n, added)
+	}
+	for i := 0; i < n; i++ {
+		if ok, _ := b.Contains([]byte(fmt.Sprintf("breached-%d", i))); !ok {
+			t.Fatalf("password %d not found", i)
+		}
+	}
+	fp := 0
+	for i := 0; i < n; i++ {
+		if ok, _ := b.Contains([]byte(fmt.Sprintf("unknown-%d", i))); ok {
+			fp++
+		}
+	}
+	if fp > n*2/100 {
+		t.Errorf("too many false positives: %d of %d", fp, n)
+	}
+}
+
+type failingBlocklist struct{}
+
+func (failingBlocklist) Contains([]byte) (bool, error) {
+	return false, errors.New("unavailable")
+}
+
+func TestPolicyBlocklist(t *testing.T) {
+	pass := []byte("Ncc1701!enterprise")
+	pol := *DefaultPolicy
+	b := NewBlocklistBloom(1, 0.01)
+	pol.Blocklist = b
+	if err := pol.Check(pass, nil, nil); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	b.Add(pass)
+	if err := pol.Check(pass, nil, nil); err != ErrBreached {
+		t.Errorf("expected ErrBreached, got %v", err)
+	}
+	if errs := pol.CheckAll(pass, nil, nil); len(errs) != 1 || errs[0] != ErrBreached {
+		t.Errorf("expected ErrBreached from CheckAll, got %v", errs)
+	}
+	pol.Blocklist = failingBlocklist{}
+	if err := pol.Check(pass, nil, nil); err != ErrFailed {
+		t.Errorf("expected ErrFailed, got %v", err)
+	}
+}
+
+func TestBlocklistFile(t *testing.T) {
+	var passwords, hashes []string
+	for i := 0; i < 1000; i++ {
+		pw := fmt.Sprintf("breached-%d", i)
+		passwords = append(passwords, pw)
+		sum := sha1.Sum([]byte(pw))
+		hashes = append(hashes, fmt.Sprintf("%X:%d", sum, i+1))
+	}
+	// A long line to test reading in chunks.
+	long := append([]string{strings.Repeat("x", 3*blocklistChunk)}, passwords...)
+	sort.Strings(long)
+	sort.Strings(hashes)
+	for _, v := range []struct {
+		lines  []string
+		sep    string
+		hashed bool
+		found  []string
+	}{
+		{long, "\n", false, long},
+		{long, "\r\n", false, long},
+		{hashes, "\r\n", true, passwords},
+	} {
+		filename := filepath.Join(t.TempDir(), "list.txt")
+		if err := os.WriteFile(filename, []byte(strings.Join(v.lines, v.sep)+v.sep), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		b, err := OpenBlocklistFile(filename, v.hashed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, pw := range v.found {
+			if ok, err := b.Contains([]byte(pw)); !ok || err != nil {
+				t.Fatalf("%q not found: %v", pw, err)
+			}
+		}
+		for _, pw := range []string{"", "a", "breached-", "breached-1000", "zzz", "breached-5 "} {
+			if ok, err := b.Contains([]byte(pw)); ok || err != nil {
+				t.Errorf("%q: unexpected result %v, %v", pw, ok, err)
+			}
+		}
+		if err := b.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pol := *DefaultPolicy
+	pol.Blocklist = NewBlocklistFile(strings.NewReader("Ncc1701!enterprise\n"), 19, false)
+	if err := pol.Check([]byte("Ncc1701!enterprise"), nil, nil); err != ErrBreached {
+		t.Errorf("expected ErrBreached, got %v", err)
+	}
+}
+
+func TestRegisterGlobalBlocklist(t *testing.T) {
+	RegisterGlobalBlocklist([]string{"Initech", "", "tps-report"})
+	t.Cleanup(func() { RegisterGlobalBlocklist(nil) })
+	for _, v := range []struct {
+		password string
+		err      error
+	}{
+		{"dw1lIojbTBrq/gii", nil},
+		{"dw1lIojbTBrq/INITECH", ErrDenied},
+		{"dw1lIojbTPS-Report", ErrDenied},
+	} {
+		if err := DefaultPolicy.CheckString(v.password, "", ""); err != v.err {
+			t.Errorf("%q: expected %v, got %v", v.password, v.err, err)
+		}
+	}
+	pol := *DefaultPolicy
+	pol.IgnoreGlobalBlocklist = true
+	if err := pol.CheckString("dw1lIojbTBrq/INITECH", "", ""); err != nil {
+		t.Errorf("no error expected with IgnoreGlobalBlocklist, got %v", err)
+	}
+	RegisterGlobalBlocklist(nil)
+	if err := DefaultPolicy.CheckString("dw1lIojbTBrq/INITECH", "", ""); err != nil {
+		t.Errorf("no error expected after removing the list, got %v", err)
+	}
+}
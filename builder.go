@@ -0,0 +1,58 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+// PolicyBuilder builds a policy from a few high-level settings, starting
+// from DefaultPolicy, so that the Min values don't have to be chosen by
+// hand. Methods return the builder, so calls can be chained:
+//
+//	p := NewPolicyBuilder().MinLength(12).RequirePassphraseWords(4).Build()
+type PolicyBuilder struct {
+	p Policy
+}
+
+// NewPolicyBuilder returns a new builder for a copy of DefaultPolicy.
+func NewPolicyBuilder() *PolicyBuilder {
+	b := new(PolicyBuilder)
+	b.p = *DefaultPolicy
+	b.p.compiled = nil
+	return b
+}
+
+// MinLength sets the minimum length of passwords using all four character
+// classes to n, and derives the other Min values from it, as in
+// DefaultPolicy, which corresponds to MinLength(7):
+//
+//	one class:     Disabled
+//	two classes:   3n+3
+//	passphrases:   n+4
+//	three classes: n+1
+//	four classes:  n
+func (b *PolicyBuilder) MinLength(n int) *PolicyBuilder {
+	b.p.Min = [5]int{Disabled, 3*n + 3, n + 4, n + 1, n}
+	return b
+}
+
+// RequirePassphraseWords sets the number of words required for a
+// passphrase, or disables user-chosen passphrases if n is 0.
+func (b *PolicyBuilder) RequirePassphraseWords(n int) *PolicyBuilder {
+	b.p.PassphraseWords = n
+	return b
+}
+
+// AllowSimilar sets whether new passwords similar to the old ones are
+// allowed (see DenySimilar).
+func (b *PolicyBuilder) AllowSimilar(allow bool) *PolicyBuilder {
+	b.p.DenySimilar = !allow
+	return b
+}
+
+// Build returns a new policy with the settings. The builder can be used
+// again to build other policies. Use Validate to check that the resulting
+// policy is valid, for example, that Max is not too small for the minimum
+// lengths.
+func (b *PolicyBuilder) Build() *Policy {
+	p := b.p
+	return &p
+}
@@ -0,0 +1,41 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import "testing"
+
+func TestPolicyBuilder(t *testing.T) {
+	if p := NewPolicyBuilder().MinLength(7).Build(); p.String() != DefaultPolicy.String() {
+		t.Errorf("MinLength(7) doesn't match DefaultPolicy: %s", p)
+	}
+	b := NewPolicyBuilder().MinLength(12).RequirePassphraseWords(4).AllowSimilar(true)
+	p := b.Build()
+	if p.Min != [5]int{Disabled, 39, 16, 13, 12} || p.PassphraseWords != 4 || p.DenySimilar {
+		t.Errorf("unexpected policy: %s", p)
+	}
+	if err := p.Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := p.CheckString("dw1lIojbTB/", "", ""); err != ErrShort {
+		t.Errorf("expected ErrShort, got %v", err)
+	}
+	if q := b.MinLength(8).Build(); q == p || p.Min[4] != 12 {
+		t.Error("Build returned shared policy")
+	}
+}
+
+func TestPolicyBuilderCompiled(t *testing.T) {
+	// Precomputed parameters of DefaultPolicy must not be copied.
+	saved := DefaultPolicy.compiled
+	t.Cleanup(func() { DefaultPolicy.compiled = saved })
+	params := DefaultPolicy.params()
+	DefaultPolicy.compiled = &params
+	p := NewPolicyBuilder().MinLength(12).Build()
+	if params := p.params(); params.min[4] != 12 {
+		t.Errorf("expected min[4] 12, got %d", params.min[4])
+	}
+	if err := p.CheckString("Kx#mLqWp2", "", ""); err != ErrShort {
+		t.Errorf("expected ErrShort, got %v", err)
+	}
+}
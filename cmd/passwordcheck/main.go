@@ -0,0 +1,123 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+// Command passwordcheck checks whether a password complies with a password
+// policy.
+//
+// Usage:
+//
+//	passwordcheck [-policy config | -policy-file file] [-user name] [-old]
+//
+// The password is read from the first line of the standard input, so that
+// it doesn't end up in the shell history. With -old, the second line is
+// read as the old password. The policy is given in the format accepted by
+// passwordcheck.ParsePolicy; by default, the default policy is used.
+//
+// The command prints "ok" and exits with status 0 if the password is
+// accepted, or prints the reason code and the message, for example,
+// "short: passwordcheck: too short", and exits with status 1 if it's
+// rejected. Other errors result in exit status 2. The password is never
+// printed.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dchest/passwordcheck"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command with the arguments and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("passwordcheck", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	config := fs.String("policy", "", "policy `config` in the format of ParsePolicy")
+	configFile := fs.String("policy-file", "", "read policy config from `file`")
+	user := fs.String("user", "", "user `name`")
+	withOld := fs.Bool("old", false, "read old password from the second line of input")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintln(stderr, "passwordcheck: unexpected arguments; the password must be given on standard input")
+		return 2
+	}
+	p, err := loadPolicy(*config, *configFile)
+	if err != nil {
+		fmt.Fprintln(stderr, "passwordcheck:", err)
+		return 2
+	}
+	lines, err := readLines(stdin, *withOld)
+	if err != nil {
+		fmt.Fprintln(stderr, "passwordcheck:", err)
+		return 2
+	}
+	var old, username []byte
+	if *withOld {
+		old = []byte(lines[1])
+	}
+	if *user != "" {
+		username = []byte(*user)
+	}
+	err = p.Check([]byte(lines[0]), old, username)
+	if err == nil {
+		fmt.Fprintln(stdout, "ok")
+		return 0
+	}
+	var e *passwordcheck.Error
+	if errors.As(err, &e) {
+		fmt.Fprintf(stdout, "%s: %s\n", e.Reason(), e)
+	} else {
+		fmt.Fprintln(stdout, err)
+	}
+	return 1
+}
+
+// loadPolicy returns the policy from the config or the config file, or
+// the default policy if both are empty.
+func loadPolicy(config, configFile string) (*passwordcheck.Policy, error) {
+	switch {
+	case config != "" && configFile != "":
+		return nil, errors.New("only one of -policy and -policy-file can be given")
+	case configFile != "":
+		b, err := os.ReadFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		config = string(b)
+	case config == "":
+		return passwordcheck.DefaultPolicy, nil
+	}
+	return passwordcheck.NewPolicy(strings.TrimSpace(config))
+}
+
+// readLines reads the new password and, if withOld is set, the old
+// password, from r.
+func readLines(r io.Reader, withOld bool) ([]string, error) {
+	n := 1
+	if withOld {
+		n = 2
+	}
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, passwordcheck.MaxPasswordLength+2)
+	for len(lines) < n && scanner.Scan() {
+		lines = append(lines, strings.TrimSuffix(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) < n {
+		return nil, errors.New("not enough lines on standard input")
+	}
+	return lines, nil
+}
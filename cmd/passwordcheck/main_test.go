@@ -0,0 +1,38 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	for _, v := range []struct {
+		args   []string
+		input  string
+		status int
+		output string
+	}{
+		{nil, "dw1lIojbTBrq/gii\n", 0, "ok\n"},
+		{nil, "sh0rt\n", 1, "short: passwordcheck: too short\n"},
+		{[]string{"-policy", "min=5,5,5,5,5 match=0"}, "zzzzz\n", 1, "simple: passwordcheck: not enough different characters or classes\n"},
+		{[]string{"-policy", "min=4,4,4,4,4 match=0"}, "84701\n", 0, "ok\n"},
+		{[]string{"-old"}, "dw1lIojbTBrq/gii\ndw1lIojbTBrq/gii\n", 1, "same: passwordcheck: is the same as the old one\n"},
+		{[]string{"-old"}, "dw1lIojbTBrq/gii\n", 2, ""},
+		{[]string{"-policy", "bogus"}, "dw1lIojbTBrq/gii\n", 2, ""},
+		{[]string{"dw1lIojbTBrq/gii"}, "", 2, ""},
+	} {
+		var stdout, stderr bytes.Buffer
+		status := run(v.args, strings.NewReader(v.input), &stdout, &stderr)
+		if status != v.status || stdout.String() != v.output {
+			t.Errorf("%q: expected %d %q, got %d %q (%s)", v.args, v.status, v.output, status, stdout.String(), stderr.String())
+		}
+		password := strings.SplitN(v.input, "\n", 2)[0]
+		if password != "" && (strings.Contains(stdout.String(), password) || strings.Contains(stderr.String(), password)) {
+			t.Errorf("%q: output contains the password", v.args)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import "strings"
+
+// Dates can be written with one of these separators between the day,
+// month, and year, or without separators.
+const dateSeparators = "/-. "
+
+// isDateLike reports whether the password is mostly a date, as described
+// for Policy.DenyDateLike.
+func isDateLike(password []byte) bool {
+	if len(password) < 6 {
+		return false
+	}
+	longest := 0
+	for i := range password {
+		if i > 0 && isDigit(password[i-1]) {
+			continue // don't split numbers
+		}
+		// Dates are 6 to 10 characters long, from "1/2/90" or "900102"
+		// to "1990-01-02".
+		for j := i + 6; j <= len(password) && j-i <= 10; j++ {
+			if j < len(password) && isDigit(password[j]) {
+				continue
+			}
+			if j-i > longest && isDate(string(password[i:j])) {
+				longest = j - i
+			}
+		}
+	}
+	return longest*4 >= len(password)*3
+}
+
+// isDate reports whether s is a date in the ISO (year, month, day), US
+// (month, day, year), or European (day, month, year) order, with or without
+// separators. Years have four digits from 1900 to 2099, or two digits;
+// without separators, the day and month must have two digits.
+func isDate(s string) bool {
+	parts := []string{s}
+	if i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		if strings.IndexByte(dateSeparators, s[i]) < 0 {
+			return false
+		}
+		parts = strings.Split(s, s[i:i+1])
+	}
+	for _, part := range parts {
+		if part == "" || strings.Trim(part, "0123456789") != "" {
+			return false
+		}
+	}
+	switch len(parts) {
+	case 1:
+		switch len(s) {
+		case 6:
+			return isYMD(s[:2], s[2:4], s[4:]) || isYMD(s[4:], s[2:4], s[:2]) || isYMD(s[4:], s[:2], s[2:4])
+		case 8:
+			return isYMD(s[:4], s[4:6], s[6:]) || isYMD(s[4:], s[2:4], s[:2]) || isYMD(s[4:], s[:2], s[2:4])
+		}
+	case 3:
+		a, b, c := parts[0], parts[1], parts[2]
+		if len(b) > 2 {
+			return false
+		}
+		if len(a) == 4 {
+			return len(c) <= 2 && isYMD(a, b, c)
+		}
+		return len(a) <= 2 && (isYMD(c, b, a) || isYMD(c, a, b))
+	}
+	return false
+}
+
+// isYMD reports whether the year, month, and day strings of digits form a
+// valid date with a plausible year.
+func isYMD(year, month, day string) bool {
+	y, m, d := atoi(year), atoi(month), atoi(day)
+	switch len(year) {
+	case 2:
+	case 4:
+		if y < 1900 || y > 2099 {
+			return false
+		}
+	default:
+		return false
+	}
+	return m >= 1 && m <= 12 && d >= 1 && d <= daysInMonth[m-1]
+}
+
+// daysInMonth are the maximum numbers of days in months, including
+// February 29.
+var daysInMonth = [...]int{31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// atoi returns the value of the string of decimal digits.
+func atoi(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		n = n*10 + int(s[i]-'0')
+	}
+	return n
+}
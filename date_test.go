@@ -0,0 +1,55 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import "testing"
+
+func TestDateLike(t *testing.T) {
+	dates := []string{
+		"01/02/1990",  // US or European
+		"12/31/1999",  // US
+		"31.12.1999",  // European
+		"31-12-99",    // European, two-digit year
+		"1/2/90",      // single-digit day and month
+		"1990-01-02",  // ISO
+		"2024/2/29",   // ISO, leap day
+		"19900102",    // ISO without separators
+		"02011990",    // European without separators
+		"12311999",    // US without separators
+		"900102",      // ISO, two-digit year
+		"311299",      // European, two-digit year
+		"19900102!",   // mostly a date
+		"x31 12 1999", // space separators
+	}
+	for _, v := range dates {
+		if !isDateLike([]byte(v)) {
+			t.Errorf("%q: expected date", v)
+		}
+	}
+	for _, v := range []string{
+		"19900102abcdef",   // date is less than three quarters
+		"13/13/1990",       // invalid month
+		"1990-02-30",       // invalid day
+		"31/12/1850",       // implausible year
+		"01/02-1990",       // mixed separators
+		"123456",           // not a date in any order
+		"1/2/9",            // too short
+		"dw1lIojbTBrq/gii", // not a date
+	} {
+		if isDateLike([]byte(v)) {
+			t.Errorf("%q: unexpected date", v)
+		}
+	}
+	pol := *DefaultPolicy
+	pol.Min = [5]int{6, 6, 6, 6, 6}
+	pol.SkipDictionary = true
+	pol.DenyDateLike = true
+	if err := pol.Check([]byte("31.12.1999!x"), nil, nil); err != ErrDate {
+		t.Errorf("expected ErrDate, got %v", err)
+	}
+	pol.DenyDateLike = false
+	if err := pol.Check([]byte("31.12.1999!x"), nil, nil); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+//go:build js && wasm
+
+// Command wasm is an example of using passwordcheck in a web browser. It
+// exposes a JavaScript function passwordCheck(newPassword, oldPassword,
+// username), which checks the password with the default policy and returns
+// an object {ok, reason, message}, like the response of the handler
+// returned by passwordcheck.NewValidatorHandler. Old password and user name
+// may be null or undefined.
+//
+// Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o passwordcheck.wasm
+//
+// and load it in a page with wasm_exec.js from $(go env GOROOT)/lib/wasm,
+// as shown in index.html.
+//
+// Since cgo is not available for WebAssembly, the pure Go port of passwdqc
+// is used. The test runs the function in a headless browser with
+// wasmbrowsertest (github.com/agnivade/wasmbrowsertest):
+//
+//	GOOS=js GOARCH=wasm go test -exec wasmbrowsertest
+//
+// or in Node.js with go_js_wasm_exec from $(go env GOROOT)/lib/wasm.
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/dchest/passwordcheck"
+)
+
+func main() {
+	register()
+	select {}
+}
+
+// register sets the global passwordCheck function.
+func register() {
+	js.Global().Set("passwordCheck", js.FuncOf(check))
+}
+
+func check(this js.Value, args []js.Value) any {
+	var pass [3][]byte
+	for i := range pass {
+		if i < len(args) && args[i].Type() == js.TypeString {
+			pass[i] = []byte(args[i].String())
+		}
+	}
+	result := map[string]any{"ok": true}
+	if err := passwordcheck.DefaultPolicy.Check(pass[0], pass[1], pass[2]); err != nil {
+		result["ok"] = false
+		result["reason"] = err.(*passwordcheck.Error).Reason().String()
+		result["message"] = err.Error()
+	}
+	return result
+}
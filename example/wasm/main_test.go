@@ -0,0 +1,34 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+func TestPasswordCheck(t *testing.T) {
+	register()
+	vectors := []struct {
+		args   []any
+		ok     bool
+		reason string
+	}{
+		{[]any{"dw1lIojbTBrq/gii"}, true, ""},
+		{[]any{"short"}, false, "short"},
+		{[]any{"dw1lIojbTBrq/gii", "dw1lIojbTBrq/gii", nil}, false, "same"},
+		{[]any{"Xq9#johnsmith", nil, "johnsmith"}, false, "personal"},
+	}
+	for i, v := range vectors {
+		r := js.Global().Call("passwordCheck", v.args...)
+		if ok := r.Get("ok").Bool(); ok != v.ok {
+			t.Errorf("%d: expected ok=%v, got %v", i, v.ok, ok)
+		}
+		if reason := r.Get("reason"); !v.ok && reason.String() != v.reason {
+			t.Errorf("%d: expected reason %q, got %s", i, v.reason, reason)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Explain returns a description of the policy requirements in English
+// suitable for showing to users, for example:
+//
+//	Passwords must be at least 24 characters long if they use two character
+//	classes, at least 8 characters long if they use three classes, and at
+//	least 7 characters long if they use four classes; passwords with one
+//	character class are not allowed. Passphrases of at least 3 words must be
+//	at least 11 characters long. Passwords must be at most 1024 characters
+//	long. The new password must not be based on the previous one.
+//
+// Character classes are digits, lower-case letters, upper-case letters,
+// and other characters.
+func (p *Policy) Explain() string {
+	if p.EntropyOnly {
+		return p.explainEntropy()
+	}
+	var allowed, denied []string
+	for i, n := range p.Min {
+		if i == 2 {
+			continue // passphrases
+		}
+		if n == Disabled {
+			denied = append(denied, classNames[i]+" character class"+plural(i))
+			continue
+		}
+		kind := classNames[i] + " classes"
+		if len(allowed) == 0 {
+			kind = classNames[i] + " character class" + plural(i)
+		}
+		allowed = append(allowed, fmt.Sprintf("at least %d characters long if they use %s", n, kind))
+	}
+	var s []string
+	switch {
+	case len(allowed) > 0:
+		t := "Passwords must be " + joinList(allowed, "and")
+		if len(denied) > 0 {
+			t += "; passwords with " + joinList(denied, "or") + " are not allowed"
+		}
+		s = append(s, t+".")
+	default:
+		s = append(s, "Only passphrases are allowed.")
+	}
+	if p.PassphraseWords > 0 && p.Min[2] != Disabled {
+		s = append(s, fmt.Sprintf("Passphrases of at least %d words must be at least %d characters long.", p.PassphraseWords, p.Min[2]))
+	} else {
+		s = append(s, "Passphrases are not accepted unless they meet the requirements for passwords.")
+	}
+	switch max := p.max(); max {
+	case Disabled:
+	case 8:
+		s = append(s, "Only the first 8 characters of passwords are used.")
+	default:
+		s = append(s, fmt.Sprintf("Passwords must be at most %d characters long.", max))
+	}
+	var required []string
+	if p.RequireDigit {
+		required = append(required, "a digit")
+	}
+	if p.RequireUpper {
+		required = append(required, "an upper-case letter")
+	}
+	if p.RequireLower {
+		required = append(required, "a lower-case letter")
+	}
+	if p.RequireSymbol {
+		required = append(required, "a symbol")
+	}
+	if len(required) > 0 {
+		s = append(s, "Passwords must contain "+joinList(required, "and")+".")
+	}
+	if p.MinUnique > 0 {
+		s = append(s, fmt.Sprintf("Passwords must contain at least %d different characters.", p.MinUnique))
+	}
+	if p.DenySimilar {
+		s = append(s, "The new password must not be based on the previous one.")
+	}
+	if p.DenyReversed {
+		s = append(s, "The new password must not contain the previous one reversed.")
+	}
+	if p.DenyShifted {
+		s = append(s, "The new password must not be the previous one with a different number at the end or with shifted characters.")
+	}
+	if p.MaxSequence > 0 {
+		s = append(s, fmt.Sprintf("Passwords must not contain more than %d sequential letters or digits, such as \"abcd\" or \"4321\".", p.MaxSequence))
+	}
+	if p.DenyKeyboardWalk {
+		s = append(s, "Passwords must not be sequences of adjacent keys on the keyboard.")
+	}
+	if p.DenyDateLike {
+		s = append(s, "Passwords must not be dates.")
+	}
+	if p.DiscountTrailingDigits {
+		s = append(s, "Digits at the end of passwords don't count toward their length.")
+	}
+	if p.ForbidUsername {
+		s = append(s, "Passwords must not contain the user name.")
+	}
+	if len(p.DenyPatterns) > 0 {
+		s = append(s, "Some passwords, such as ones containing denied words, are not allowed.")
+	}
+	return strings.Join(s, " ")
+}
+
+// explainEntropy returns the description of the policy in EntropyOnly mode.
+func (p *Policy) explainEntropy() string {
+	s := fmt.Sprintf("Passwords must have at least %d bits of randomness.", p.MinEntropyBits)
+	if max := p.max(); max != Disabled {
+		s += fmt.Sprintf(" Passwords must be at most %d characters long.", max)
+	}
+	return s
+}
+
+// classNames are the numbers of character classes for indexes of Min.
+var classNames = [...]string{"one", "two", "", "three", "four"}
+
+func plural(i int) string {
+	if i == 0 {
+		return ""
+	}
+	return "es"
+}
+
+// joinList joins a list of phrases with commas and the conjunction.
+func joinList(a []string, conj string) string {
+	switch len(a) {
+	case 1:
+		return a[0]
+	case 2:
+		return a[0] + " " + conj + " " + a[1]
+	}
+	return strings.Join(a[:len(a)-1], ", ") + ", " + conj + " " + a[len(a)-1]
+}
@@ -0,0 +1,47 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	expected := "Passwords must be at least 24 characters long if they use two character classes, " +
+		"at least 8 characters long if they use three classes, and at least 7 characters long if they use four classes; " +
+		"passwords with one character class are not allowed. " +
+		"Passphrases of at least 3 words must be at least 11 characters long. " +
+		"Passwords must be at most 1024 characters long. " +
+		"The new password must not be based on the previous one."
+	if s := DefaultPolicy.Explain(); s != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, s)
+	}
+	p := MustParsePolicy("min=disabled,disabled,12,disabled,disabled max=0 passphrase=4 similar=permit username=deny")
+	expected = "Only passphrases are allowed. " +
+		"Passphrases of at least 4 words must be at least 12 characters long. " +
+		"Passwords must not contain the user name."
+	if s := p.Explain(); s != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, s)
+	}
+}
+
+func TestExplainRequired(t *testing.T) {
+	p := *DefaultPolicy
+	p.RequireDigit = true
+	if s := p.Explain(); !strings.Contains(s, " Passwords must contain a digit.") {
+		t.Errorf("expected required digit, got:\n%s", s)
+	}
+	p.RequireUpper, p.RequireLower, p.RequireSymbol = true, true, true
+	expected := " Passwords must contain a digit, an upper-case letter, a lower-case letter, and a symbol."
+	if s := p.Explain(); !strings.Contains(s, expected) {
+		t.Errorf("expected required classes, got:\n%s", s)
+	}
+	p = *DefaultPolicy
+	p.DenyPatterns = []*regexp.Regexp{regexp.MustCompile(`(?i)acme`)}
+	if s := p.Explain(); !strings.Contains(s, " Some passwords, such as ones containing denied words, are not allowed.") {
+		t.Errorf("expected denied patterns, got:\n%s", s)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/dchest/passwordcheck
+
+go 1.21
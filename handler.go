@@ -0,0 +1,76 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// maxRequestSize limits the size of requests accepted by the validator
+// handler: enough for three strings of MaxPasswordLength bytes each, even
+// if every byte is escaped in JSON.
+const maxRequestSize = 3*6*MaxPasswordLength + 1024
+
+type validatorRequest struct {
+	Password    string `json:"password"`
+	OldPassword string `json:"oldPassword"`
+	Username    string `json:"username"`
+}
+
+type validatorResponse struct {
+	OK      bool   `json:"ok"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// NewValidatorHandler returns an HTTP handler, which checks passwords with
+// the policy p, for example, for live validation in web applications.
+//
+// The handler accepts POST requests with a JSON object in the body:
+//
+//	{"password": "...", "oldPassword": "...", "username": "..."}
+//
+// Fields oldPassword and username are optional. It responds with a JSON
+// object, where reason is the code of the reason returned by Reason.String
+// and message is the error message:
+//
+//	{"ok": false, "reason": "short", "message": "passwordcheck: too short"}
+//
+// or with {"ok": true} if the password complies with the policy.
+//
+// Responses are marked as not cacheable. The handler doesn't log requests,
+// so passwords are never logged by it, but beware of logging by other
+// handlers and proxies.
+func NewValidatorHandler(p *Policy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req validatorRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var oldPassword, username []byte
+		if req.OldPassword != "" {
+			oldPassword = []byte(req.OldPassword)
+		}
+		if req.Username != "" {
+			username = []byte(req.Username)
+		}
+		var resp validatorResponse
+		if err := p.Check([]byte(req.Password), oldPassword, username); err != nil {
+			resp.Reason = err.(*Error).Reason().String()
+			resp.Message = err.Error()
+		} else {
+			resp.OK = true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&resp)
+	})
+}
@@ -0,0 +1,46 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidatorHandler(t *testing.T) {
+	h := NewValidatorHandler(DefaultPolicy)
+	vectors := []struct {
+		method, body string
+		code         int
+		resp         validatorResponse
+	}{
+		{"POST", `{"password": "dw1lIojbTBrq/gii"}`, 200, validatorResponse{OK: true}},
+		{"POST", `{"password": "short"}`, 200, validatorResponse{Reason: "short", Message: ErrShort.Error()}},
+		{"POST", `{"password": "dw1lIojbTBrq/gii", "oldPassword": "dw1lIojbTBrq/gii"}`, 200, validatorResponse{Reason: "same", Message: ErrSame.Error()}},
+		{"POST", `{"password": `, 400, validatorResponse{}},
+		{"GET", ``, 405, validatorResponse{}},
+	}
+	for i, v := range vectors {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(v.method, "/", strings.NewReader(v.body)))
+		if w.Code != v.code {
+			t.Errorf("%d: expected status %d, got %d", i, v.code, w.Code)
+		}
+		if cc := w.Header().Get("Cache-Control"); cc != "no-store" {
+			t.Errorf("%d: expected no-store, got %q", i, cc)
+		}
+		if w.Code != 200 {
+			continue
+		}
+		var resp validatorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if resp != v.resp {
+			t.Errorf("%d: expected %+v, got %+v", i, v.resp, resp)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseHistoryFile reads a list of previous passwords or password hashes
+// from r, for example, to reject reused passwords with Check or, for
+// hashes, with CheckAgainstHashes. Blank lines and lines starting with '#'
+// are skipped. Other lines are parsed as follows:
+//
+//   - Lines in the format of /etc/security/opasswd used by pam_unix and
+//     pam_pwhistory, "user:uid:count:hash1,hash2,...", give all the hashes
+//     they list.
+//   - Lines in the format of /etc/shadow, with nine colon-separated fields,
+//     give the hash from the second field, unless it's empty or starts
+//     with '*' or '!', which mean that there's no usable password.
+//   - Any other line is returned as is, so files with one password or hash
+//     per line are supported.
+//
+// Since plain passwords may contain colons, files with one password per
+// line must not contain passwords that look like the above formats.
+func ParseHistoryFile(r io.Reader) ([][]byte, error) {
+	var history [][]byte
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || line[0] == '#' {
+			continue
+		}
+		for _, s := range historyEntries(line) {
+			history = append(history, []byte(s))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// historyEntries returns the passwords or hashes from the history line.
+func historyEntries(line string) []string {
+	fields := strings.Split(line, ":")
+	switch {
+	case len(fields) == 4 && isNumber(fields[1]) && isNumber(fields[2]):
+		// opasswd: user:uid:count:hash1,hash2,...
+		var hashes []string
+		for _, h := range strings.Split(fields[3], ",") {
+			if h != "" {
+				hashes = append(hashes, h)
+			}
+		}
+		return hashes
+	case len(fields) == 9:
+		// shadow: user:hash:lastchg:min:max:warn:inactive:expire:reserved
+		if h := fields[1]; h != "" && h[0] != '*' && h[0] != '!' {
+			return []string{h}
+		}
+		return nil
+	}
+	return []string{line}
+}
+
+// isNumber reports whether s is a non-negative decimal number.
+func isNumber(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
@@ -0,0 +1,39 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHistoryFile(t *testing.T) {
+	const file = `# previous passwords
+dw1lIojbTBrq/gii
+
+alice:1000:2:$6$salt$hash1,$6$salt$hash2
+bob:$6$salt$hash3:19000:0:99999:7:::
+locked:!$6$salt$hash4:19000:0:99999:7:::
+nopass:*:19000:0:99999:7:::
+plain:with:colons` + "\r\n"
+	history, err := ParseHistoryFile(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, h := range history {
+		got = append(got, string(h))
+	}
+	expected := []string{
+		"dw1lIojbTBrq/gii",
+		"$6$salt$hash1",
+		"$6$salt$hash2",
+		"$6$salt$hash3",
+		"plain:with:colons",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
@@ -0,0 +1,135 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// PolicyHolder holds a policy that can be replaced at runtime.
+// It is safe for concurrent use by multiple goroutines.
+//
+// The zero value holds DefaultPolicy.
+type PolicyHolder struct {
+	p atomic.Pointer[Policy]
+}
+
+// NewPolicyHolder returns a new holder with the given policy.
+func NewPolicyHolder(p *Policy) *PolicyHolder {
+	h := new(PolicyHolder)
+	h.Store(p)
+	return h
+}
+
+// Load returns the current policy.
+//
+// The returned policy must not be modified.
+func (h *PolicyHolder) Load() *Policy {
+	if p := h.p.Load(); p != nil {
+		return p
+	}
+	return DefaultPolicy
+}
+
+// Store replaces the current policy with p.
+//
+// The policy must not be modified after storing it.
+func (h *PolicyHolder) Store(p *Policy) {
+	h.p.Store(p)
+}
+
+// WatchFile loads the policy from the named file, stores it in the holder,
+// and then checks the file for changes every interval, reloading the policy
+// when the file's size or modification time change. The file must contain
+// a policy in the format accepted by ParsePolicy; the dictionary file it
+// names, if any, is read on every reload.
+//
+// If the file cannot be read or parsed during reloading, the current policy
+// is kept and the error is passed to onError, if it's not nil.
+//
+// WatchFile returns an error if the initial loading fails. Otherwise it
+// returns a function, which stops watching the file.
+func (h *PolicyHolder) WatchFile(filename string, interval time.Duration, onError func(error)) (stop func(), err error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.loadFile(filename); err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+			cur, err := os.Stat(filename)
+			if err == nil {
+				if cur.Size() == fi.Size() && cur.ModTime().Equal(fi.ModTime()) {
+					continue
+				}
+				fi = cur
+				err = h.loadFile(filename)
+			}
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}()
+	var stopped atomic.Bool
+	return func() {
+		if stopped.CompareAndSwap(false, true) {
+			close(done)
+		}
+	}, nil
+}
+
+// loadFile loads the policy from the named file into the holder.
+func (h *PolicyHolder) loadFile(filename string) error {
+	config, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	p, err := ParsePolicy(strings.TrimSpace(string(config)))
+	if err != nil {
+		return err
+	}
+	if err := p.LoadDictionaryFile(); err != nil {
+		return err
+	}
+	h.Store(p)
+	return nil
+}
+
+// PolicySet selects policies by user role, for example, to require stronger
+// passwords for administrators than for regular users.
+//
+// A PolicySet must not be modified while it's used concurrently.
+type PolicySet struct {
+	// Default is the policy for roles not in Roles. If it's nil,
+	// DefaultPolicy is used.
+	Default *Policy
+
+	// Roles maps role names to their policies.
+	Roles map[string]*Policy
+}
+
+// For returns the policy for the role, or the default policy if there is
+// no policy for the role.
+func (s *PolicySet) For(role string) *Policy {
+	if p := s.Roles[role]; p != nil {
+		return p
+	}
+	if s.Default != nil {
+		return s.Default
+	}
+	return DefaultPolicy
+}
@@ -0,0 +1,99 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPolicyHolder(t *testing.T) {
+	var h PolicyHolder
+	if h.Load() != DefaultPolicy {
+		t.Error("expected DefaultPolicy in zero holder")
+	}
+	p := MustParsePolicy("max=20")
+	h.Store(p)
+	if h.Load() != p {
+		t.Error("expected stored policy")
+	}
+}
+
+func TestPolicyHolderWatchFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "policy")
+	if err := os.WriteFile(filename, []byte("max=20\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var h PolicyHolder
+	errs := make(chan error, 10)
+	stop, err := h.WatchFile(filename, 10*time.Millisecond, func(err error) { errs <- err })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	if h.Load().Max != 20 {
+		t.Fatalf("expected max 20, got %d", h.Load().Max)
+	}
+
+	update := func(config string, mtime time.Time) {
+		if err := os.WriteFile(filename, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filename, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitMax := func(max int) {
+		for i := 0; i < 100; i++ {
+			if h.Load().Max == max {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("expected max %d, got %d", max, h.Load().Max)
+	}
+
+	now := time.Now()
+	update("max=30\n", now.Add(time.Second))
+	waitMax(30)
+
+	update("max=thirty\n", now.Add(2*time.Second))
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("expected error")
+	}
+	if h.Load().Max != 30 {
+		t.Errorf("expected previous policy to be kept, got max %d", h.Load().Max)
+	}
+
+	update("max=40\n", now.Add(3*time.Second))
+	waitMax(40)
+
+	if _, err := h.WatchFile(filepath.Join(t.TempDir(), "missing"), time.Second, nil); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestPolicySet(t *testing.T) {
+	admin := MustParsePolicy("min=disabled,disabled,20,16,14")
+	user := MustParsePolicy("max=64")
+	s := &PolicySet{Roles: map[string]*Policy{"admin": admin}}
+	if s.For("admin") != admin {
+		t.Error("expected admin policy")
+	}
+	if s.For("user") != DefaultPolicy || s.For("") != DefaultPolicy {
+		t.Error("expected DefaultPolicy for unknown role")
+	}
+	s.Default = user
+	if s.For("user") != user {
+		t.Error("expected default policy for unknown role")
+	}
+	var empty PolicySet
+	if empty.For("admin") != DefaultPolicy {
+		t.Error("expected DefaultPolicy in zero set")
+	}
+}
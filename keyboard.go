@@ -0,0 +1,68 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+// keyboardLayout describes the positions of keys on a keyboard.
+type keyboardLayout struct {
+	pos map[byte][2]int // row and column of each key
+}
+
+// newKeyboardLayout returns a layout with the given rows of unshifted and
+// shifted characters. Each row is assumed to be offset by half a key to the
+// right of the row above it, as on a standard keyboard.
+func newKeyboardLayout(rows, shiftedRows []string) *keyboardLayout {
+	kl := &keyboardLayout{pos: make(map[byte][2]int)}
+	for _, keys := range [][]string{rows, shiftedRows} {
+		for i, row := range keys {
+			for j := 0; j < len(row); j++ {
+				kl.pos[row[j]] = [2]int{i, j}
+			}
+		}
+	}
+	return kl
+}
+
+// qwerty is the US QWERTY keyboard layout.
+var qwerty = newKeyboardLayout(
+	[]string{"1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"},
+	[]string{"!@#$%^&*()_+", "QWERTYUIOP{}|", "ASDFGHJKL:\"", "ZXCVBNM<>?"},
+)
+
+// adjacent reports whether keys for a and b are next to each other.
+func (kl *keyboardLayout) adjacent(a, b byte) bool {
+	pa, ok := kl.pos[a]
+	if !ok {
+		return false
+	}
+	pb, ok := kl.pos[b]
+	if !ok {
+		return false
+	}
+	dr, dc := pb[0]-pa[0], pb[1]-pa[1]
+	switch dr {
+	case 0:
+		return dc == 1 || dc == -1
+	case 1: // the row below is shifted to the right
+		return dc == 0 || dc == -1
+	case -1:
+		return dc == 0 || dc == 1
+	}
+	return false
+}
+
+// isWalk reports whether the password is mostly a sequence of adjacent
+// keys, as described for Policy.DenyKeyboardWalk. Characters not on the
+// keyboard, including non-ASCII ones, are never adjacent to other keys.
+func (kl *keyboardLayout) isWalk(password []byte) bool {
+	if len(password) < 4 {
+		return false
+	}
+	pairs := 0
+	for i := 1; i < len(password); i++ {
+		if kl.adjacent(password[i-1], password[i]) {
+			pairs++
+		}
+	}
+	return pairs*4 >= (len(password)-1)*3
+}
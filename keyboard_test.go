@@ -0,0 +1,41 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import "testing"
+
+func TestKeyboardWalk(t *testing.T) {
+	walks := []string{
+		"qwerty",
+		"asdfgh",
+		"1qaz2wsx",
+		"QWERTYUIOP",
+		"!QAZ@WSX",
+		"zaq12wsx",
+		"poiuytrewq",
+		"1q2w3e4r5t",
+		"qwertyuiop[]",
+	}
+	for _, v := range walks {
+		if !qwerty.isWalk([]byte(v)) {
+			t.Errorf("%q: expected keyboard walk", v)
+		}
+	}
+	for _, v := range []string{"qwe", "password", "dw1lIojbTBrq/gii", "aaaaaaaa", "qwerty-Xk9#mL2v"} {
+		if qwerty.isWalk([]byte(v)) {
+			t.Errorf("%q: unexpected keyboard walk", v)
+		}
+	}
+	pol := *DefaultPolicy
+	pol.Min = [5]int{6, 6, 6, 6, 6}
+	pol.SkipDictionary = true
+	pol.DenyKeyboardWalk = true
+	if err := pol.Check([]byte("1qaz2wsx3edc"), nil, nil); err != ErrKeyboard {
+		t.Errorf("expected ErrKeyboard, got %v", err)
+	}
+	pol.DenyKeyboardWalk = false
+	if err := pol.Check([]byte("1qaz2wsx3edc"), nil, nil); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+}
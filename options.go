@@ -0,0 +1,48 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+// Option is a setting of a policy created with NewPolicyWith.
+type Option func(*Policy)
+
+// NewPolicyWith returns a copy of DefaultPolicy with the options applied in
+// order. It is an alternative to ParsePolicy for configuring policies in
+// Go code:
+//
+//	p := NewPolicyWith(WithMin([5]int{Disabled, 30, 14, 10, 9}), WithMax(64))
+//
+// The resulting policy is not validated; use Validate to check it.
+func NewPolicyWith(opts ...Option) *Policy {
+	p := *DefaultPolicy
+	p.compiled = nil
+	for _, opt := range opts {
+		opt(&p)
+	}
+	return &p
+}
+
+// WithMin sets Min.
+func WithMin(min [5]int) Option {
+	return func(p *Policy) { p.Min = min }
+}
+
+// WithMax sets Max.
+func WithMax(max int) Option {
+	return func(p *Policy) { p.Max = max }
+}
+
+// WithPassphraseWords sets PassphraseWords.
+func WithPassphraseWords(n int) Option {
+	return func(p *Policy) { p.PassphraseWords = n }
+}
+
+// WithMatchLength sets MatchLength.
+func WithMatchLength(n int) Option {
+	return func(p *Policy) { p.MatchLength = n }
+}
+
+// WithSimilarDeny sets DenySimilar.
+func WithSimilarDeny(deny bool) Option {
+	return func(p *Policy) { p.DenySimilar = deny }
+}
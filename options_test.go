@@ -0,0 +1,32 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import "testing"
+
+func TestNewPolicyWith(t *testing.T) {
+	if p := NewPolicyWith(); p.String() != DefaultPolicy.String() || p == DefaultPolicy {
+		t.Errorf("expected a copy of DefaultPolicy, got %s", p)
+	}
+	p := NewPolicyWith(
+		WithMin([5]int{Disabled, 30, 14, 10, 9}),
+		WithMax(64),
+		WithPassphraseWords(4),
+		WithMatchLength(5),
+		WithSimilarDeny(false),
+	)
+	want, err := ParsePolicy("min=disabled,30,14,10,9 max=64 passphrase=4 match=5 similar=permit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != want.String() {
+		t.Errorf("expected %s, got %s", want, p)
+	}
+	if err := p.Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := p.CheckString("dw1lIo/B", "", ""); err != ErrShort {
+		t.Errorf("expected ErrShort, got %v", err)
+	}
+}
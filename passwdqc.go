@@ -0,0 +1,671 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+//
+// Go port of passwdqc_check.c, copyright (c) 2000-2002,2010,2013 by Solar
+// Designer. It is used when cgo is not available, for example, when
+// compiling to WebAssembly, and must be kept in sync with the C code.
+
+package passwordcheck
+
+import (
+	"strconv"
+	"strings"
+)
+
+// passwdqcVersion is the version of passwdqc the port is based on, the same
+// as PASSWDQC_VERSION in passwdqc.h.
+const passwdqcVersion = "1.3.0"
+
+// Reasons returned by passwdqc, the same as REASON_* in passwdqc_check.c.
+const (
+	reasonError       = "check failed"
+	reasonSame        = "is the same as the old one"
+	reasonSimilar     = "is based on the old one"
+	reasonShort       = "too short"
+	reasonLong        = "too long"
+	reasonSimpleShort = "not enough different characters or classes for this length"
+	reasonSimple      = "not enough different characters or classes"
+	reasonPersonal    = "based on personal login information"
+	reasonWord        = "based on a dictionary word and not a passphrase"
+	reasonSeq         = "based on a common sequence of characters and not a passphrase"
+)
+
+// Flags for failed checks returned by checkAll, the same as
+// PASSWDQC_FAILED_* in passwdqc.h.
+const (
+	failedError       = 0x001
+	failedSame        = 0x002
+	failedSimilar     = 0x004
+	failedShort       = 0x008
+	failedLong        = 0x010
+	failedSimpleShort = 0x020
+	failedSimple      = 0x040
+	failedPersonal    = 0x080
+	failedWord        = 0x100
+	failedSeq         = 0x200
+)
+
+// qcParams are passwdqc parameters, the same as passwdqc_params_qc_t.
+type qcParams struct {
+	min                  [5]int32
+	max                  int32
+	passphraseWords      int32
+	passphraseMinWordLen int32
+	matchLength          int32
+	similarMatchLength   int32 // matchLength for the old password
+	similarDeny          bool
+	nonASCIILetters      bool
+	skipSame             bool // the caller compares newpass with oldpass
+	unifyMap             [0x100]byte
+}
+
+// qcStats are password statistics, the same as passwdqc_stats_t.
+type qcStats struct {
+	length, words, chars                     int
+	digits, lowers, uppers, others, unknowns int
+	classes                                  int
+}
+
+// Character classification for the C locale, as in <ctype.h>.
+
+func isASCII(c byte) bool { return c < 0x80 }
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+func isLower(c byte) bool { return c >= 'a' && c <= 'z' }
+func isUpper(c byte) bool { return c >= 'A' && c <= 'Z' }
+func isAlpha(c byte) bool { return isLower(c) || isUpper(c) }
+
+func isSpace(c byte) bool {
+	return c == ' ' || c >= '\t' && c <= '\r'
+}
+
+const fixedBits = 15
+
+// expectedDifferent calculates the expected number of different characters
+// for a random password of a given length. The result is rounded down. We
+// use this with the _requested_ minimum length (so longer passwords don't
+// have to meet this strict requirement for their length).
+func expectedDifferent(charset, length int) int {
+	x := (uint64(charset-1) << fixedBits) / uint64(charset)
+	y := x
+	for length--; length > 0; length-- {
+		y = (y * x) >> fixedBits
+	}
+	z := uint64(charset) * ((1 << fixedBits) - y)
+	return int(z >> fixedBits)
+}
+
+// decodeUTF8 decodes the UTF-8 sequence at the beginning of s and returns
+// the code point and the length of the sequence, or 0 if it's not a valid
+// multibyte sequence. Unlike utf8.DecodeRuneInString, it doesn't reject
+// overlong encodings and surrogates, in the same way as passwdqc.
+func decodeUTF8(s string) (cp rune, n int) {
+	switch c := s[0]; {
+	case c >= 0xc2 && c <= 0xdf:
+		cp, n = rune(c&0x1f), 2
+	case c >= 0xe0 && c <= 0xef:
+		cp, n = rune(c&0x0f), 3
+	case c >= 0xf0 && c <= 0xf4:
+		cp, n = rune(c&0x07), 4
+	default:
+		return 0, 0
+	}
+	for i := 1; i < n; i++ {
+		if i >= len(s) || s[i]&0xc0 != 0x80 {
+			return 0, 0
+		}
+		cp = cp<<6 | rune(s[i]&0x3f)
+	}
+	return cp, n
+}
+
+// isUpperNonASCII reports whether the non-ASCII code point is an upper case
+// letter of the Latin-1 Supplement, Latin Extended-A, Greek, or Cyrillic
+// blocks. Other non-ASCII characters are treated as lower case letters.
+func isUpperNonASCII(cp rune) bool {
+	switch {
+	case cp >= 0xc0 && cp <= 0xde:
+		return cp != 0xd7
+	case cp >= 0x100 && cp <= 0x137, cp >= 0x14a && cp <= 0x177:
+		return cp&1 == 0
+	case cp >= 0x139 && cp <= 0x148, cp >= 0x179 && cp <= 0x17e:
+		return cp&1 == 1
+	case cp == 0x178:
+		return true
+	case cp >= 0x391 && cp <= 0x3a9:
+		return cp != 0x3a2
+	}
+	return cp >= 0x400 && cp <= 0x42f
+}
+
+// statsGo counts characters of each class, words, and different characters
+// in a password, and the number of character classes that count toward its
+// strength. Words shorter than minWordLen characters are not counted. If
+// nonASCIILetters is set, valid UTF-8 sequences are counted as upper or
+// lower case letters instead of as non-ASCII characters.
+func statsGo(pass string, minWordLen int, nonASCIILetters bool) (st qcStats) {
+	if minWordLen < 1 {
+		minWordLen = 1
+	}
+	run, cont := 0, 0
+	firstUpper := false
+	p := byte(' ')
+	for i := 0; i < len(pass); i++ {
+		c := pass[i]
+		switch {
+		case !isASCII(c) && nonASCIILetters && cont > 0:
+			cont-- // counted with the first byte
+		case !isASCII(c) && nonASCIILetters && decodeLetter(pass[i:], &cont, &st):
+			if i == 0 && st.uppers == 1 {
+				firstUpper = true
+			}
+		case !isASCII(c):
+			st.unknowns++
+		case isDigit(c):
+			st.digits++
+		case isLower(c):
+			st.lowers++
+		case isUpper(c):
+			st.uppers++
+		default:
+			st.others++
+		}
+
+		// A word starts when a letter follows a non-letter or when a
+		// non-ASCII character follows a space character, and it's counted
+		// once it is minWordLen characters long.
+		if isASCII(p) && (isASCII(c) && isAlpha(c) && !isAlpha(p) || !isASCII(c) && isSpace(p)) {
+			run = 1
+		} else if run > 0 && (!isASCII(c) || isAlpha(c)) {
+			run++
+		} else {
+			run = 0
+		}
+		if run == minWordLen {
+			st.words++
+		}
+		p = c
+
+		// Count this character just once: when we're not going to see it
+		// anymore.
+		if strings.IndexByte(pass[i+1:], c) < 0 {
+			st.chars++
+		}
+	}
+	st.length = len(pass)
+	if st.length == 0 {
+		return
+	}
+
+	// Upper case characters and digits used in common ways don't increase
+	// the strength of a password.
+	digits, uppers := st.digits, st.uppers
+	if uppers > 0 && (firstUpper || isUpper(pass[0])) {
+		uppers--
+	}
+	if digits > 0 && isDigit(pass[len(pass)-1]) {
+		digits--
+	}
+
+	// Count the number of different character classes we've seen. We
+	// assume that there are no non-ASCII characters for digits.
+	for _, n := range []int{digits, st.lowers, uppers, st.others} {
+		if n > 0 {
+			st.classes++
+		}
+	}
+	if st.unknowns > 0 && st.classes <= 1 && (st.classes == 0 || digits > 0 || st.words >= 2) {
+		st.classes++
+	}
+	return
+}
+
+// decodeLetter decodes the UTF-8 sequence at the beginning of s and counts
+// it in st as an upper or lower case letter, setting cont to the number of
+// its remaining bytes. It returns false if s doesn't start with a valid
+// multibyte sequence.
+func decodeLetter(s string, cont *int, st *qcStats) bool {
+	cp, n := decodeUTF8(s)
+	if n == 0 {
+		return false
+	}
+	*cont = n - 1
+	if isUpperNonASCII(cp) {
+		st.uppers++
+	} else {
+		st.lowers++
+	}
+	return true
+}
+
+// isSimple reports whether a password is too short for its class, or
+// doesn't contain enough different characters for its class, or doesn't
+// contain enough words for a passphrase.
+//
+// The biases are added to the length, and they may be positive or
+// negative. The passphrase length check uses passphraseBias instead of bias
+// so that zero may be passed for this parameter when the (other) bias is
+// non-zero because of a dictionary word, which is perfectly normal for a
+// passphrase. The biases do not affect the number of different characters,
+// character classes, and word count.
+func (params *qcParams) isSimple(newpass string, bias, passphraseBias int) bool {
+	st := statsGo(newpass, int(params.passphraseMinWordLen), params.nonASCIILetters)
+	if st.length == 0 {
+		return true
+	}
+	minLen := func(i int) int { return int(params.min[i]) }
+	for classes := st.classes; classes > 0; classes-- {
+		switch classes {
+		case 1:
+			return !(st.length+bias >= minLen(0) &&
+				st.chars >= expectedDifferent(10, minLen(0))-1)
+		case 2:
+			if st.length+bias >= minLen(1) &&
+				st.chars >= expectedDifferent(36, minLen(1))-1 {
+				return false
+			}
+			if params.passphraseWords == 0 ||
+				st.words < int(params.passphraseWords) {
+				continue
+			}
+			if st.length+passphraseBias >= minLen(2) &&
+				st.chars >= expectedDifferent(27, minLen(2))-1 {
+				return false
+			}
+		case 3:
+			if st.length+bias >= minLen(3) &&
+				st.chars >= expectedDifferent(62, minLen(3))-1 {
+				return false
+			}
+		case 4:
+			if st.length+bias >= minLen(4) &&
+				st.chars >= expectedDifferent(95, minLen(4))-1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// unifyMap returns the map used by unify. When foldCase is set, upper case
+// letters are mapped to lower case. When leet is set, letters are mapped to
+// the characters commonly substituted for them.
+func unifyMap(foldCase, leet bool) (m [0x100]byte) {
+	for i := range m {
+		c := byte(i)
+		if foldCase && isUpper(c) {
+			c += 'a' - 'A'
+		}
+		if leet {
+			switch c {
+			case 'a', '@':
+				c = '4'
+			case 'e':
+				c = '3'
+			// Unfortunately, if we translate both 'i' and 'l' to '1', this
+			// would associate these two letters with each other - e.g.,
+			// "mile" would match "MLLE", which is undesired. To solve this,
+			// we'd need to test different translations separately, which is
+			// not implemented yet.
+			case 'i', '|':
+				c = '!'
+			case 'l':
+				c = '1'
+			case 'o':
+				c = '0'
+			case 's', '$':
+				c = '5'
+			case 't', '+':
+				c = '7'
+			}
+		}
+		m[i] = c
+	}
+	return
+}
+
+func (params *qcParams) unify(s string) string {
+	b := make([]byte, len(s))
+	for i := 0; i < len(s); i++ {
+		b[i] = params.unifyMap[s[i]]
+	}
+	return string(b)
+}
+
+func reverse(s string) string {
+	b := make([]byte, len(s))
+	for i := 0; i < len(s); i++ {
+		b[len(s)-1-i] = s[i]
+	}
+	return string(b)
+}
+
+// isBased reports whether needle is based on haystack, that is, both
+// contain a long enough common substring and needle would be too simple for
+// a password with the substring either removed with partial length credit
+// for it added or partially discounted for the purpose of the length check.
+//
+// If effective is not nil, the length check is not performed; instead,
+// *effective is lowered to the shortest length, taking the credit or
+// discount into account, that the password would be checked with.
+func (params *qcParams) isBased(haystack, needle, original string, mode int, effective *int) bool {
+	if params.matchLength == 0 { // disabled
+		return false
+	}
+	if params.matchLength < 0 { // misconfigured
+		return true
+	}
+	matchLength := int(params.matchLength)
+	worstBias := 0
+	length := len(needle)
+	for i := 0; i <= length-matchLength; i++ {
+	lengths:
+		for j := matchLength; i+j <= length; j++ {
+			bias := 0
+			sub := needle[i : i+j]
+			for p := 0; p < len(haystack); p++ {
+				if !strings.HasPrefix(haystack[p:], sub) {
+					continue
+				}
+				if mode&0xff == 0 { // remove & credit
+					// remove j chars
+					pos := length - (i + j)
+					if mode&0x100 == 0 { // not reversed
+						pos = i
+					}
+					scratch := original[:pos] + original[pos+j:]
+					// add credit for matchLength - 1 chars
+					bias = matchLength - 1
+					if effective != nil {
+						if length-j+bias < *effective {
+							*effective = length - j + bias
+						}
+					} else if params.isSimple(scratch, bias, bias) {
+						return true
+					}
+				} else { // discount
+					// Require a 1 character longer match for substrings
+					// containing leetspeak when matching against dictionary
+					// words.
+					bias = -1
+					if mode&0xff == 1 { // words
+						pos, end := i, i+j
+						if mode&0x100 != 0 { // reversed
+							pos, end = length-end, length-i
+						}
+						for ; pos < end; pos++ {
+							if !isAlpha(original[pos]) {
+								if j == matchLength {
+									continue lengths
+								}
+								bias = 0
+								break
+							}
+						}
+					}
+					// discount j - (matchLength + bias) chars
+					bias += matchLength - j
+					// bias <= -1
+					if bias < worstBias {
+						passphraseBias := bias
+						if mode&0xff == 1 {
+							passphraseBias = 0
+						}
+						if effective != nil {
+							if length+bias < *effective {
+								*effective = length + bias
+							}
+						} else if params.isSimple(original, bias, passphraseBias) {
+							return true
+						}
+						worstBias = bias
+					}
+				}
+			}
+			// Zero bias implies that there were no matches for this
+			// length. If so, there's no reason to try the next substring
+			// length (it would result in no matches as well). We break out
+			// of the substring length loop and proceed with all substring
+			// lengths for the next position in needle.
+			if bias == 0 {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// seq are common sequences of characters, as in passwdqc_check.c, which
+// explains why they are sufficient.
+var seq = [...]string{
+	"0123456789",
+	"`1234567890-=",
+	"~!@#$%^&*()_+",
+	"abcdefghijklmnopqrstuvwxyz",
+	"a1b2c3d4e5f6g7h8i9j0",
+	"1a2b3c4d5e6f7g8h9i0j",
+	"abc123",
+	"qwertyuiop[]\\asdfghjkl;'zxcvbnm,./",
+	"qwertyuiop{}|asdfghjkl:\"zxcvbnm<>?",
+	"qwertyuiopasdfghjklzxcvbnm",
+	"1qaz2wsx3edc4rfv5tgb6yhn7ujm8ik,9ol.0p;/-['=]\\",
+	"!qaz@wsx#edc$rfv%tgb^yhn&ujm*ik<(ol>)p:?_{\"+}|",
+	"qazwsxedcrfvtgbyhnujmikolp",
+	"1q2w3e4r5t6y7u8i9o0p-[=]",
+	"q1w2e3r4t5y6u7i8o9p0[-]=\\",
+	"1qaz1qaz",
+	"1qaz!qaz", // can't unify '1' and '!' - see comment in unifyMap
+	"1qazzaq1",
+	"zaq!1qaz",
+	"zaq!2wsx",
+}
+
+// similar returns params for comparing the new password with the old one,
+// that is, params with matchLength replaced by similarMatchLength.
+func (params *qcParams) similar() *qcParams {
+	if params.similarMatchLength == params.matchLength {
+		return params
+	}
+	sp := *params
+	sp.matchLength = params.similarMatchLength
+	return &sp
+}
+
+const (
+	wordBasedWords = 1
+	wordBasedSeq   = 2
+)
+
+// isWordBased returns reasonWord or reasonSeq if needle is based on a word
+// from wordset4k or on a common sequence of characters, respectively, or an
+// empty string if it is not.
+func (params *qcParams) isWordBased(needle, original string, isReversed, what int, effective *int) string {
+	if params.matchLength == 0 { // disabled
+		return ""
+	}
+	mode := isReversed | 1
+	if what&wordBasedWords != 0 {
+		for i, word := range wordset4k {
+			if len(word) < int(params.matchLength) {
+				continue
+			}
+			if i < 0xfff && strings.HasPrefix(wordset4k[i+1], word) {
+				continue
+			}
+			if params.isBased(params.unify(word), needle, original, mode, effective) {
+				return reasonWord
+			}
+		}
+	}
+	mode = isReversed | 2
+	if what&wordBasedSeq != 0 {
+		for _, s := range seq {
+			if params.isBased(params.unify(s), needle, original, mode, effective) {
+				return reasonSeq
+			}
+		}
+		if params.matchLength <= 4 {
+			for i := 1900; i <= 2039; i++ {
+				if params.isBased(strconv.Itoa(i), needle, original, mode, effective) {
+					return reasonSeq
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// basedOnGo reports whether newpass is based on source the same way checkGo
+// determines that a new password is based on the old one or on the user
+// name.
+func basedOnGo(params *qcParams, newpass, source []byte) bool {
+	np := string(newpass)
+	uNewpass := params.unify(np)
+	uSource := params.unify(string(source))
+	return params.isBased(uSource, uNewpass, np, 0, nil) ||
+		params.isBased(uSource, reverse(uNewpass), np, 0x100, nil)
+}
+
+// checkGo checks the new password and returns the reason for rejecting it
+// or an empty string if it's accepted. Old password and name are not used
+// if they are nil.
+func checkGo(params *qcParams, newpass, oldpass, name []byte) string {
+	np := string(newpass)
+	if oldpass != nil && !params.skipSame && string(oldpass) == np {
+		return reasonSame
+	}
+	length := len(np)
+	if length > MaxPasswordLength {
+		return reasonLong
+	}
+	if length < int(params.min[4]) {
+		return reasonShort
+	}
+	if length > int(params.max) {
+		if params.max != 8 {
+			return reasonLong
+		}
+		np = np[:8]
+		if oldpass != nil && !params.skipSame && strings.HasPrefix(string(oldpass), np) {
+			return reasonSame
+		}
+	}
+	if params.isSimple(np, 0, 0) {
+		if length < int(params.min[1]) && params.min[1] <= params.max {
+			return reasonSimpleShort
+		}
+		return reasonSimple
+	}
+	uNewpass := params.unify(np)
+	uReversed := reverse(uNewpass)
+	if oldpass != nil && params.similarDeny {
+		uOldpass := params.unify(string(oldpass))
+		if params.similar().isBased(uOldpass, uNewpass, np, 0, nil) ||
+			params.similar().isBased(uOldpass, uReversed, np, 0x100, nil) {
+			return reasonSimilar
+		}
+	}
+	if name != nil {
+		uName := params.unify(string(name))
+		if params.isBased(uName, uNewpass, np, 0, nil) ||
+			params.isBased(uName, uReversed, np, 0x100, nil) {
+			return reasonPersonal
+		}
+	}
+	reason := params.isWordBased(uNewpass, np, 0, wordBasedWords|wordBasedSeq, nil)
+	if reason == "" {
+		reason = params.isWordBased(uReversed, np, 0x100, wordBasedWords|wordBasedSeq, nil)
+	}
+	return reason
+}
+
+// checkAllGo is like checkGo, but performs all checks independently instead
+// of stopping at the first failed one, and returns a combination of failed*
+// flags for the failed checks.
+func checkAllGo(params *qcParams, newpass, oldpass, name []byte) (failed uint) {
+	np := string(newpass)
+	if oldpass != nil && !params.skipSame && string(oldpass) == np {
+		failed |= failedSame
+	}
+	length := len(np)
+	if length < int(params.min[4]) {
+		failed |= failedShort
+	}
+	if length > MaxPasswordLength {
+		failed |= failedLong
+	} else if length > int(params.max) {
+		if params.max == 8 {
+			np = np[:8]
+			if oldpass != nil && !params.skipSame && strings.HasPrefix(string(oldpass), np) {
+				failed |= failedSame
+			}
+		} else {
+			failed |= failedLong
+		}
+	}
+	if params.isSimple(np, 0, 0) {
+		if length < int(params.min[1]) && params.min[1] <= params.max {
+			failed |= failedSimpleShort
+		} else {
+			failed |= failedSimple
+		}
+	}
+	uNewpass := params.unify(np)
+	uReversed := reverse(uNewpass)
+	// The same password is obviously similar, so don't report it twice.
+	if oldpass != nil && params.similarDeny && failed&failedSame == 0 {
+		uOldpass := params.unify(string(oldpass))
+		if params.similar().isBased(uOldpass, uNewpass, np, 0, nil) ||
+			params.similar().isBased(uOldpass, uReversed, np, 0x100, nil) {
+			failed |= failedSimilar
+		}
+	}
+	if name != nil {
+		uName := params.unify(string(name))
+		if params.isBased(uName, uNewpass, np, 0, nil) ||
+			params.isBased(uName, uReversed, np, 0x100, nil) {
+			failed |= failedPersonal
+		}
+	}
+	if params.isWordBased(uNewpass, np, 0, wordBasedWords, nil) != "" ||
+		params.isWordBased(uReversed, np, 0x100, wordBasedWords, nil) != "" {
+		failed |= failedWord
+	}
+	if params.isWordBased(uNewpass, np, 0, wordBasedSeq, nil) != "" ||
+		params.isWordBased(uReversed, np, 0x100, wordBasedSeq, nil) != "" {
+		failed |= failedSeq
+	}
+	return
+}
+
+// effectiveLengthGo returns the shortest length, taking the credit or
+// discount for common substrings with the old password (if similar
+// passwords are denied), the name, and, if dictionary is set, dictionary
+// words and common sequences of characters into account, that checkGo
+// compares with the minimum lengths when checking newpass, or -1 if the
+// parameters are misconfigured. Old password and name are not used if
+// they are nil.
+func effectiveLengthGo(params *qcParams, newpass, oldpass, name []byte, dictionary bool) int {
+	np := string(newpass)
+	if len(np) > int(params.max) && params.max == 8 {
+		np = np[:8]
+	}
+	effective := len(np)
+	uNewpass := params.unify(np)
+	uReversed := reverse(uNewpass)
+	if oldpass != nil && params.similarDeny {
+		uOldpass := params.unify(string(oldpass))
+		params.similar().isBased(uOldpass, uNewpass, np, 0, &effective)
+		params.similar().isBased(uOldpass, uReversed, np, 0x100, &effective)
+	}
+	if name != nil {
+		uName := params.unify(string(name))
+		params.isBased(uName, uNewpass, np, 0, &effective)
+		params.isBased(uName, uReversed, np, 0x100, &effective)
+	}
+	if dictionary &&
+		(params.isWordBased(uNewpass, np, 0, wordBasedWords|wordBasedSeq, &effective) != "" ||
+			params.isWordBased(uReversed, np, 0x100, wordBasedWords|wordBasedSeq, &effective) != "") {
+		return -1
+	}
+	return effective
+}
@@ -7,17 +7,58 @@
 #ifndef PASSWDQC_H__
 #define PASSWDQC_H__
 
+/* Version of passwdqc this modified source is based on */
+#define PASSWDQC_VERSION		"1.3.0"
+
+/* Passwords longer than this are always rejected as too long */
+#define PASSWDQC_MAX_LENGTH		10000
+
 typedef struct {
 	int min[5], max;
 	int passphrase_words;
+	int passphrase_min_word_len;
 	int match_length;
+	int similar_match_length; /* match_length for the old password */
 	int similar_deny;
+	int non_ascii_letters;
+	int skip_same; /* the caller compares newpass with oldpass */
 	int random_bits; // unused
+	unsigned char unify_map[0x100]; /* filled by unifyMap() in passwdqc.go */
 } passwdqc_params_qc_t;
 
+typedef struct {
+	int length, words, chars;
+	int digits, lowers, uppers, others, unknowns;
+	int classes;
+} passwdqc_stats_t;
+
 const char *passwdqc_check(const passwdqc_params_qc_t *params,
     const char *newpass, const char *oldpass, const char *name);
 
+#define PASSWDQC_FAILED_ERROR		0x001
+#define PASSWDQC_FAILED_SAME		0x002
+#define PASSWDQC_FAILED_SIMILAR		0x004
+#define PASSWDQC_FAILED_SHORT		0x008
+#define PASSWDQC_FAILED_LONG		0x010
+#define PASSWDQC_FAILED_SIMPLESHORT	0x020
+#define PASSWDQC_FAILED_SIMPLE		0x040
+#define PASSWDQC_FAILED_PERSONAL	0x080
+#define PASSWDQC_FAILED_WORD		0x100
+#define PASSWDQC_FAILED_SEQ		0x200
+
+unsigned int passwdqc_check_all(const passwdqc_params_qc_t *params,
+    const char *newpass, const char *oldpass, const char *name);
+
+int passwdqc_based_on(const passwdqc_params_qc_t *params,
+    const char *newpass, const char *source);
+
+void passwdqc_stats(const char *pass, int min_word_len,
+    int non_ascii_letters, passwdqc_stats_t *stats);
+
+int passwdqc_effective_length(const passwdqc_params_qc_t *params,
+    const char *newpass, const char *oldpass, const char *name,
+    int dictionary);
+
 void passwdqc_free(char *dst);
 
 extern const char *REASON_ERROR;
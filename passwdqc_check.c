@@ -10,6 +10,11 @@
 #include "passwdqc.h"
 #include "wordset_4k.h"
 
+/* isascii() is POSIX rather than ISO C and may be missing on Windows */
+#ifndef isascii
+#define isascii(c) (((c) & ~0x7f) == 0)
+#endif
+
 const char *REASON_ERROR = "check failed";
 const char *REASON_SAME = "is the same as the old one";
 const char *REASON_SIMILAR = "is based on the old one";
@@ -47,31 +52,91 @@ static int expected_different(int charset, int length)
 }
 
 /*
- * A password is too simple if it is too short for its class, or doesn't
- * contain enough different characters for its class, or doesn't contain
- * enough words for a passphrase.
- *
- * The biases are added to the length, and they may be positive or negative.
- * The passphrase length check uses passphrase_bias instead of bias so that
- * zero may be passed for this parameter when the (other) bias is non-zero
- * because of a dictionary word, which is perfectly normal for a passphrase.
- * The biases do not affect the number of different characters, character
- * classes, and word count.
+ * Decodes the UTF-8 sequence at s into *cp and returns its length, or
+ * returns 0 if it's not a valid multibyte sequence.
  */
-static int is_simple(const passwdqc_params_qc_t *params, const char *newpass,
-    int bias, int passphrase_bias)
+static int decode_utf8(const unsigned char *s, int *cp)
 {
-	int length, classes, words, chars;
+	int n, i;
+
+	if (s[0] >= 0xc2 && s[0] <= 0xdf) {
+		n = 2;
+		*cp = s[0] & 0x1f;
+	} else if (s[0] >= 0xe0 && s[0] <= 0xef) {
+		n = 3;
+		*cp = s[0] & 0x0f;
+	} else if (s[0] >= 0xf0 && s[0] <= 0xf4) {
+		n = 4;
+		*cp = s[0] & 0x07;
+	} else
+		return 0;
+	for (i = 1; i < n; i++) {
+		if ((s[i] & 0xc0) != 0x80)
+			return 0;
+		*cp = (*cp << 6) | (s[i] & 0x3f);
+	}
+	return n;
+}
+
+/*
+ * Returns whether the non-ASCII code point is an upper case letter of the
+ * Latin-1 Supplement, Latin Extended-A, Greek, or Cyrillic blocks.  Other
+ * non-ASCII characters are treated as lower case letters.
+ */
+static int is_upper_non_ascii(int cp)
+{
+	if (cp >= 0xc0 && cp <= 0xde)
+		return cp != 0xd7;
+	if (cp >= 0x100 && cp <= 0x137)
+		return !(cp & 1);
+	if (cp >= 0x139 && cp <= 0x148)
+		return cp & 1;
+	if (cp >= 0x14a && cp <= 0x177)
+		return !(cp & 1);
+	if (cp == 0x178)
+		return 1;
+	if (cp >= 0x179 && cp <= 0x17e)
+		return cp & 1;
+	if (cp >= 0x391 && cp <= 0x3a9)
+		return cp != 0x3a2;
+	return cp >= 0x400 && cp <= 0x42f;
+}
+
+/*
+ * Counts characters of each class, words, and different characters in a
+ * password, and the number of character classes that count toward its
+ * strength.  Words shorter than min_word_len characters are not counted.
+ * If non_ascii_letters is set, valid UTF-8 sequences are counted as upper
+ * or lower case letters instead of as non-ASCII characters.
+ */
+void passwdqc_stats(const char *pass, int min_word_len,
+    int non_ascii_letters, passwdqc_stats_t *stats)
+{
+	int length, words, chars, run;
 	int digits, lowers, uppers, others, unknowns;
+	int classes, first_upper, cont, n, cp;
 	int c, p;
 
-	length = classes = words = chars = 0;
+	length = words = chars = run = 0;
 	digits = lowers = uppers = others = unknowns = 0;
+	first_upper = cont = 0;
 	p = ' ';
-	while ((c = (unsigned char)newpass[length])) {
+	while ((c = (unsigned char)pass[length])) {
 		length++;
 
-		if (!isascii(c))
+		if (!isascii(c) && non_ascii_letters && cont) {
+			cont--; /* counted with the first byte */
+		} else if (!isascii(c) && non_ascii_letters &&
+		    (n = decode_utf8((const unsigned char *)&pass[length - 1],
+		    &cp))) {
+			cont = n - 1;
+			if (is_upper_non_ascii(cp)) {
+				uppers++;
+				if (length == 1)
+					first_upper = 1;
+			} else
+				lowers++;
+		} else if (!isascii(c))
 			unknowns++;
 		else if (isdigit(c))
 			digits++;
@@ -86,29 +151,43 @@ static int is_simple(const passwdqc_params_qc_t *params, const char *newpass,
  * character follows a space character.  We treat all non-ASCII characters
  * as non-spaces, which is not entirely correct (there's the non-breaking
  * space character at 0xa0, 0x9a, or 0xff), but it should not hurt. */
-		if (isascii(p)) {
-			if (isascii(c)) {
-				if (isalpha(c) && !isalpha(p))
-					words++;
-			} else if (isspace(p))
-				words++;
-		}
+/* A word is counted once it is min_word_len characters long; its letters
+ * and non-ASCII characters continue it. */
+		if (isascii(p) && (isascii(c) ?
+		    isalpha(c) && !isalpha(p) : isspace(p)))
+			run = 1;
+		else if (run && (!isascii(c) || isalpha(c)))
+			run++;
+		else
+			run = 0;
+		if (run && run == (min_word_len > 1 ? min_word_len : 1))
+			words++;
 		p = c;
 
 /* Count this character just once: when we're not going to see it anymore */
-		if (!strchr(&newpass[length], c))
+		if (!strchr(&pass[length], c))
 			chars++;
 	}
 
+	stats->length = length;
+	stats->words = words;
+	stats->chars = chars;
+	stats->digits = digits;
+	stats->lowers = lowers;
+	stats->uppers = uppers;
+	stats->others = others;
+	stats->unknowns = unknowns;
+	stats->classes = 0;
+
 	if (!length)
-		return 1;
+		return;
 
 /* Upper case characters and digits used in common ways don't increase the
  * strength of a password */
-	c = (unsigned char)newpass[0];
-	if (uppers && isascii(c) && isupper(c))
+	c = (unsigned char)pass[0];
+	if (uppers && (first_upper || (isascii(c) && isupper(c))))
 		uppers--;
-	c = (unsigned char)newpass[length - 1];
+	c = (unsigned char)pass[length - 1];
 	if (digits && isascii(c) && isdigit(c))
 		digits--;
 
@@ -126,6 +205,37 @@ static int is_simple(const passwdqc_params_qc_t *params, const char *newpass,
 	if (unknowns && classes <= 1 && (!classes || digits || words >= 2))
 		classes++;
 
+	stats->classes = classes;
+}
+
+/*
+ * A password is too simple if it is too short for its class, or doesn't
+ * contain enough different characters for its class, or doesn't contain
+ * enough words for a passphrase.
+ *
+ * The biases are added to the length, and they may be positive or negative.
+ * The passphrase length check uses passphrase_bias instead of bias so that
+ * zero may be passed for this parameter when the (other) bias is non-zero
+ * because of a dictionary word, which is perfectly normal for a passphrase.
+ * The biases do not affect the number of different characters, character
+ * classes, and word count.
+ */
+static int is_simple(const passwdqc_params_qc_t *params, const char *newpass,
+    int bias, int passphrase_bias)
+{
+	passwdqc_stats_t stats;
+	int length, classes, words, chars;
+
+	passwdqc_stats(newpass, params->passphrase_min_word_len,
+	    params->non_ascii_letters, &stats);
+	length = stats.length;
+	classes = stats.classes;
+	words = stats.words;
+	chars = stats.chars;
+
+	if (!length)
+		return 1;
+
 	for (; classes > 0; classes--)
 	switch (classes) {
 	case 1:
@@ -162,11 +272,11 @@ static int is_simple(const passwdqc_params_qc_t *params, const char *newpass,
 	return 1;
 }
 
-static char *unify(char *dst, const char *src)
+static char *unify(const passwdqc_params_qc_t *params, char *dst,
+    const char *src)
 {
 	const char *sptr;
 	char *dptr;
-	int c;
 
 	if (!dst && !(dst = malloc(strlen(src) + 1)))
 		return NULL;
@@ -174,30 +284,7 @@ static char *unify(char *dst, const char *src)
 	sptr = src;
 	dptr = dst;
 	do {
-		c = (unsigned char)*sptr;
-		if (isascii(c) && isupper(c))
-			c = tolower(c);
-		switch (c) {
-		case 'a': case '@':
-			c = '4'; break;
-		case 'e':
-			c = '3'; break;
-/* Unfortunately, if we translate both 'i' and 'l' to '1', this would
- * associate these two letters with each other - e.g., "mile" would
- * match "MLLE", which is undesired.  To solve this, we'd need to test
- * different translations separately, which is not implemented yet. */
-		case 'i': case '|':
-			c = '!'; break;
-		case 'l':
-			c = '1'; break;
-		case 'o':
-			c = '0'; break;
-		case 's': case '$':
-			c = '5'; break;
-		case 't': case '+':
-			c = '7'; break;
-		}
-		*dptr++ = c;
+		*dptr++ = params->unify_map[(unsigned char)*sptr];
 	} while (*sptr++);
 
 	return dst;
@@ -239,10 +326,14 @@ void passwdqc_free(char *dst)
  * substring and needle would be too simple for a password with the
  * substring either removed with partial length credit for it added
  * or partially discounted for the purpose of the length check.
+ *
+ * If effective is not NULL, the length check is not performed; instead,
+ * *effective is lowered to the shortest length, taking the credit or
+ * discount into account, that the password would be checked with.
  */
 static int is_based(const passwdqc_params_qc_t *params,
     const char *haystack, const char *needle, const char *original,
-    int mode)
+    int mode, int *effective)
 {
 	char *scratch;
 	int length;
@@ -283,7 +374,10 @@ static int is_based(const passwdqc_params_qc_t *params,
 				}
 				/* add credit for match_length - 1 chars */
 				bias = params->match_length - 1;
-				if (is_simple(params, scratch, bias, bias)) {
+				if (effective) {
+					if (length - j + bias < *effective)
+						*effective = length - j + bias;
+				} else if (is_simple(params, scratch, bias, bias)) {
 					passwdqc_free(scratch);
 					return 1;
 				}
@@ -311,7 +405,10 @@ static int is_based(const passwdqc_params_qc_t *params,
 				bias += (int)params->match_length - j;
 				/* bias <= -1 */
 				if (bias < worst_bias) {
-					if (is_simple(params, original, bias,
+					if (effective) {
+						if (length + bias < *effective)
+							*effective = length + bias;
+					} else if (is_simple(params, original, bias,
 					    (mode & 0xff) == 1 ? 0 : bias))
 						return 1;
 					worst_bias = bias;
@@ -333,6 +430,21 @@ next_match_length:
 	return 0;
 }
 
+/*
+ * Returns params for comparing the new password with the old one, that is,
+ * params with match_length replaced by similar_match_length, copied to buf
+ * if they differ.
+ */
+static const passwdqc_params_qc_t *similar_params(
+    const passwdqc_params_qc_t *params, passwdqc_params_qc_t *buf)
+{
+	if (params->similar_match_length == params->match_length)
+		return params;
+	*buf = *params;
+	buf->match_length = params->similar_match_length;
+	return buf;
+}
+
 /*
  * Common sequences of characters.
  * We don't need to list any of the entire strings in reverse order because the
@@ -359,7 +471,7 @@ const char * const seq[] = {
 	"1q2w3e4r5t6y7u8i9o0p-[=]",
 	"q1w2e3r4t5y6u7i8o9p0[-]=\\",
 	"1qaz1qaz",
-	"1qaz!qaz", /* can't unify '1' and '!' - see comment in unify() */
+	"1qaz!qaz", /* can't unify '1' and '!' - see comment in unifyMap() in passwdqc.go */
 	"1qazzaq1",
 	"zaq!1qaz",
 	"zaq!2wsx"
@@ -377,8 +489,12 @@ const char * const seq[] = {
  * that aren't short English words.  Perhaps support for large wordlists
  * should still be added, even though this is now of little importance.
  */
+#define WORD_BASED_WORDS		1
+#define WORD_BASED_SEQ			2
+
 static const char *is_word_based(const passwdqc_params_qc_t *params,
-    const char *needle, const char *original, int is_reversed)
+    const char *needle, const char *original, int is_reversed, int what,
+    int *effective)
 {
 	char word[WORDSET_4K_LENGTH_MAX + 1];
 	char *unified;
@@ -391,6 +507,7 @@ static const char *is_word_based(const passwdqc_params_qc_t *params,
 
 	mode = is_reversed | 1;
 	word[WORDSET_4K_LENGTH_MAX] = '\0';
+	if (what & WORD_BASED_WORDS)
 	for (i = 0; i < 0x1000; i++) {
 		memcpy(word, _passwdqc_wordset_4k[i], WORDSET_4K_LENGTH_MAX);
 		length = strlen(word);
@@ -399,39 +516,77 @@ static const char *is_word_based(const passwdqc_params_qc_t *params,
 		if (i < 0xfff &&
 		    !memcmp(word, _passwdqc_wordset_4k[i + 1], length))
 			continue;
-		unify(word, word);
-		if (is_based(params, word, needle, original, mode))
+		unify(params, word, word);
+		if (is_based(params, word, needle, original, mode, effective))
 			return REASON_WORD;
 	}
 
 	mode = is_reversed | 2;
+	if (what & WORD_BASED_SEQ)
 	for (i = 0; i < sizeof(seq) / sizeof(seq[0]); i++) {
-		unified = unify(NULL, seq[i]);
+		unified = unify(params, NULL, seq[i]);
 		if (!unified)
 			return REASON_ERROR;
-		if (is_based(params, unified, needle, original, mode)) {
+		if (is_based(params, unified, needle, original, mode,
+		    effective)) {
 			free(unified);
 			return REASON_SEQ;
 		}
 		free(unified);
 	}
 
-	if (params->match_length <= 4)
+	if ((what & WORD_BASED_SEQ) && params->match_length <= 4)
 	for (i = 1900; i <= 2039; i++) {
 		sprintf(word, "%u", i);
-		if (is_based(params, word, needle, original, mode))
+		if (is_based(params, word, needle, original, mode, effective))
 			return REASON_SEQ;
 	}
 
 	return NULL;
 }
 
+/*
+ * Returns 1 if newpass is based on source the same way passwdqc_check()
+ * determines that a new password is based on the old one or on the user
+ * name, 0 if it is not, or -1 on error.
+ */
+int passwdqc_based_on(const passwdqc_params_qc_t *params,
+    const char *newpass, const char *source)
+{
+	char *u_newpass, *u_reversed;
+	char *u_source;
+	int result;
+
+	u_newpass = u_reversed = NULL;
+	u_source = NULL;
+
+	result = -1;
+
+	if (!(u_newpass = unify(params, NULL, newpass)))
+		goto out;
+	if (!(u_reversed = reverse(u_newpass)))
+		goto out;
+	if (!(u_source = unify(params, NULL, source)))
+		goto out;
+
+	result = is_based(params, u_source, u_newpass, newpass, 0, NULL) ||
+	    is_based(params, u_source, u_reversed, newpass, 0x100, NULL);
+
+out:
+	passwdqc_free(u_newpass);
+	passwdqc_free(u_reversed);
+	passwdqc_free(u_source);
+
+	return result;
+}
+
 const char *passwdqc_check(const passwdqc_params_qc_t *params,
     const char *newpass, const char *oldpass, const char *name)
 {
 	char truncated[9];
 	char *u_newpass, *u_reversed;
 	char *u_oldpass;
+	passwdqc_params_qc_t similar;
 	char *u_name;
 	const char *reason;
 	int length;
@@ -442,13 +597,18 @@ const char *passwdqc_check(const passwdqc_params_qc_t *params,
 
 	reason = REASON_ERROR;
 
-	if (oldpass && !strcmp(oldpass, newpass)) {
+	if (oldpass && !params->skip_same && !strcmp(oldpass, newpass)) {
 		reason = REASON_SAME;
 		goto out;
 	}
 
 	length = strlen(newpass);
 
+	if (length > PASSWDQC_MAX_LENGTH) {
+		reason = REASON_LONG;
+		goto out;
+	}
+
 	if (length < params->min[4]) {
 		reason = REASON_SHORT;
 		goto out;
@@ -459,7 +619,8 @@ const char *passwdqc_check(const passwdqc_params_qc_t *params,
 			truncated[0] = '\0';
 			strncat(truncated, newpass, 8);
 			newpass = truncated;
-			if (oldpass && !strncmp(oldpass, newpass, 8)) {
+			if (oldpass && !params->skip_same &&
+			    !strncmp(oldpass, newpass, 8)) {
 				reason = REASON_SAME;
 				goto out;
 			}
@@ -476,32 +637,34 @@ const char *passwdqc_check(const passwdqc_params_qc_t *params,
 		goto out;
 	}
 
-	if (!(u_newpass = unify(NULL, newpass)))
+	if (!(u_newpass = unify(params, NULL, newpass)))
 		goto out; /* REASON_ERROR */
 	if (!(u_reversed = reverse(u_newpass)))
 		goto out;
-	if (oldpass && !(u_oldpass = unify(NULL, oldpass)))
+	if (oldpass && !(u_oldpass = unify(params, NULL, oldpass)))
 		goto out;
-	if (name && !(u_name = unify(NULL, name)))
+	if (name && !(u_name = unify(params, NULL, name)))
 		goto out;
 
 	if (oldpass && params->similar_deny &&
-	    (is_based(params, u_oldpass, u_newpass, newpass, 0) ||
-	     is_based(params, u_oldpass, u_reversed, newpass, 0x100))) {
+	    (is_based(similar_params(params, &similar), u_oldpass, u_newpass, newpass, 0, NULL) ||
+	     is_based(similar_params(params, &similar), u_oldpass, u_reversed, newpass, 0x100, NULL))) {
 		reason = REASON_SIMILAR;
 		goto out;
 	}
 
 	if (name &&
-	    (is_based(params, u_name, u_newpass, newpass, 0) ||
-	     is_based(params, u_name, u_reversed, newpass, 0x100))) {
+	    (is_based(params, u_name, u_newpass, newpass, 0, NULL) ||
+	     is_based(params, u_name, u_reversed, newpass, 0x100, NULL))) {
 		reason = REASON_PERSONAL;
 		goto out;
 	}
 
-	reason = is_word_based(params, u_newpass, newpass, 0);
+	reason = is_word_based(params, u_newpass, newpass, 0,
+	    WORD_BASED_WORDS | WORD_BASED_SEQ, NULL);
 	if (!reason)
-		reason = is_word_based(params, u_reversed, newpass, 0x100);
+		reason = is_word_based(params, u_reversed, newpass, 0x100,
+		    WORD_BASED_WORDS | WORD_BASED_SEQ, NULL);
 
 out:
 	burn(truncated, sizeof(truncated));
@@ -512,3 +675,185 @@ out:
 
 	return reason;
 }
+
+/*
+ * Like passwdqc_check(), but performs all checks independently instead of
+ * stopping at the first failed one, and returns a combination of
+ * PASSWDQC_FAILED_* flags for the failed checks.
+ */
+unsigned int passwdqc_check_all(const passwdqc_params_qc_t *params,
+    const char *newpass, const char *oldpass, const char *name)
+{
+	char truncated[9];
+	char *u_newpass, *u_reversed;
+	char *u_oldpass;
+	passwdqc_params_qc_t similar;
+	char *u_name;
+	const char *reason;
+	unsigned int failed;
+	int length;
+
+	u_newpass = u_reversed = NULL;
+	u_oldpass = NULL;
+	u_name = NULL;
+
+	failed = 0;
+
+	if (oldpass && !params->skip_same && !strcmp(oldpass, newpass))
+		failed |= PASSWDQC_FAILED_SAME;
+
+	length = strlen(newpass);
+
+	if (length < params->min[4])
+		failed |= PASSWDQC_FAILED_SHORT;
+
+	if (length > PASSWDQC_MAX_LENGTH) {
+		failed |= PASSWDQC_FAILED_LONG;
+	} else if (length > params->max) {
+		if (params->max == 8) {
+			truncated[0] = '\0';
+			strncat(truncated, newpass, 8);
+			newpass = truncated;
+			if (oldpass && !params->skip_same &&
+			    !strncmp(oldpass, newpass, 8))
+				failed |= PASSWDQC_FAILED_SAME;
+		} else {
+			failed |= PASSWDQC_FAILED_LONG;
+		}
+	}
+
+	if (is_simple(params, newpass, 0, 0)) {
+		if (length < params->min[1] && params->min[1] <= params->max)
+			failed |= PASSWDQC_FAILED_SIMPLESHORT;
+		else
+			failed |= PASSWDQC_FAILED_SIMPLE;
+	}
+
+	if (!(u_newpass = unify(params, NULL, newpass)))
+		goto error;
+	if (!(u_reversed = reverse(u_newpass)))
+		goto error;
+	if (oldpass && !(u_oldpass = unify(params, NULL, oldpass)))
+		goto error;
+	if (name && !(u_name = unify(params, NULL, name)))
+		goto error;
+
+/* The same password is obviously similar, so don't report it twice */
+	if (oldpass && params->similar_deny &&
+	    !(failed & PASSWDQC_FAILED_SAME) &&
+	    (is_based(similar_params(params, &similar), u_oldpass, u_newpass, newpass, 0, NULL) ||
+	     is_based(similar_params(params, &similar), u_oldpass, u_reversed, newpass, 0x100, NULL)))
+		failed |= PASSWDQC_FAILED_SIMILAR;
+
+	if (name &&
+	    (is_based(params, u_name, u_newpass, newpass, 0, NULL) ||
+	     is_based(params, u_name, u_reversed, newpass, 0x100, NULL)))
+		failed |= PASSWDQC_FAILED_PERSONAL;
+
+	reason = is_word_based(params, u_newpass, newpass, 0,
+	    WORD_BASED_WORDS, NULL);
+	if (!reason)
+		reason = is_word_based(params, u_reversed, newpass, 0x100,
+		    WORD_BASED_WORDS, NULL);
+	if (reason == REASON_WORD)
+		failed |= PASSWDQC_FAILED_WORD;
+	else if (reason)
+		goto error;
+
+	reason = is_word_based(params, u_newpass, newpass, 0,
+	    WORD_BASED_SEQ, NULL);
+	if (!reason)
+		reason = is_word_based(params, u_reversed, newpass, 0x100,
+		    WORD_BASED_SEQ, NULL);
+	if (reason == REASON_SEQ)
+		failed |= PASSWDQC_FAILED_SEQ;
+	else if (reason)
+		goto error;
+
+	goto out;
+
+error:
+	failed |= PASSWDQC_FAILED_ERROR;
+
+out:
+	burn(truncated, sizeof(truncated));
+	passwdqc_free(u_newpass);
+	passwdqc_free(u_reversed);
+	passwdqc_free(u_oldpass);
+	passwdqc_free(u_name);
+
+	return failed;
+}
+
+/*
+ * Returns the shortest length, taking the credit or discount for common
+ * substrings with the old password (if similar passwords are denied), the
+ * name, and, if dictionary is non-zero, dictionary words and common
+ * sequences of characters into account, that passwdqc_check() compares
+ * with the minimum lengths when checking newpass, or -1 on error.
+ */
+int passwdqc_effective_length(const passwdqc_params_qc_t *params,
+    const char *newpass, const char *oldpass, const char *name,
+    int dictionary)
+{
+	char truncated[9];
+	char *u_newpass, *u_reversed;
+	char *u_oldpass;
+	passwdqc_params_qc_t similar;
+	char *u_name;
+	int effective;
+
+	u_newpass = u_reversed = NULL;
+	u_oldpass = NULL;
+	u_name = NULL;
+
+	effective = strlen(newpass);
+	if (effective > params->max && params->max == 8) {
+		truncated[0] = '\0';
+		strncat(truncated, newpass, 8);
+		newpass = truncated;
+		effective = 8;
+	}
+
+	if (!(u_newpass = unify(params, NULL, newpass)))
+		goto error;
+	if (!(u_reversed = reverse(u_newpass)))
+		goto error;
+	if (oldpass && !(u_oldpass = unify(params, NULL, oldpass)))
+		goto error;
+	if (name && !(u_name = unify(params, NULL, name)))
+		goto error;
+
+	if (oldpass && params->similar_deny) {
+		is_based(similar_params(params, &similar), u_oldpass, u_newpass, newpass, 0, &effective);
+		is_based(similar_params(params, &similar), u_oldpass, u_reversed, newpass, 0x100,
+		    &effective);
+	}
+
+	if (name) {
+		is_based(params, u_name, u_newpass, newpass, 0, &effective);
+		is_based(params, u_name, u_reversed, newpass, 0x100,
+		    &effective);
+	}
+
+	if (dictionary &&
+	    (is_word_based(params, u_newpass, newpass, 0,
+	     WORD_BASED_WORDS | WORD_BASED_SEQ, &effective) ||
+	     is_word_based(params, u_reversed, newpass, 0x100,
+	     WORD_BASED_WORDS | WORD_BASED_SEQ, &effective)))
+		goto error;
+
+	goto out;
+
+error:
+	effective = -1;
+
+out:
+	burn(truncated, sizeof(truncated));
+	passwdqc_free(u_newpass);
+	passwdqc_free(u_reversed);
+	passwdqc_free(u_oldpass);
+	passwdqc_free(u_name);
+
+	return effective;
+}
@@ -7,51 +7,228 @@
 // Package passwordcheck is a password and passphrase strength checker based on
 // passwdqc (http://www.openwall.com/passwdqc/).
 //
-// Currently implemented via a CGO-binding to a modified passwdqc.
+// When cgo is available, it is implemented via a CGO-binding to a modified
+// passwdqc. Otherwise, for example, when compiling to WebAssembly, a pure Go
+// port of passwdqc with the same API and results is used.
 package passwordcheck
 
-// #include <limits.h>   // for INT_MAX
-// #include "passwdqc.h"
-import "C"
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf16"
 )
 
+// Reason identifies the reason for rejecting a password.
+type Reason int
+
+const (
+	ReasonNone        Reason = iota // password accepted
+	ReasonUnknown                   // unrecognized reason
+	ReasonEmpty                     // empty password
+	ReasonFailed                    // check failed
+	ReasonSame                      // same as the old one
+	ReasonSimilar                   // based on the old one
+	ReasonShort                     // too short
+	ReasonLong                      // too long
+	ReasonSimpleShort               // not enough different characters or classes for this length
+	ReasonSimple                    // not enough different characters or classes
+	ReasonPersonal                  // based on user name
+	ReasonWord                      // based on a dictionary word and not a passphrase
+	ReasonSeq                       // based on a common sequence of characters and not a passphrase
+	ReasonNul                       // contains NUL byte
+	ReasonNoDigit                   // no digits
+	ReasonNoUpper                   // no upper-case letters
+	ReasonNoLower                   // no lower-case letters
+	ReasonNoSymbol                  // no symbols
+	ReasonBreached                  // found in a blocklist
+	ReasonFewUnique                 // not enough unique characters
+	ReasonKeyboard                  // keyboard walk
+	ReasonDenied                    // matches a denied pattern
+	ReasonTruncated                 // will be truncated (a warning)
+	ReasonDate                      // date
+)
+
+var reasonNames = [...]string{
+	ReasonNone:        "none",
+	ReasonUnknown:     "unknown",
+	ReasonEmpty:       "empty",
+	ReasonFailed:      "failed",
+	ReasonSame:        "same",
+	ReasonSimilar:     "similar",
+	ReasonShort:       "short",
+	ReasonLong:        "long",
+	ReasonSimpleShort: "simpleshort",
+	ReasonSimple:      "simple",
+	ReasonPersonal:    "personal",
+	ReasonWord:        "word",
+	ReasonSeq:         "seq",
+	ReasonNul:         "nul",
+	ReasonNoDigit:     "nodigit",
+	ReasonNoUpper:     "noupper",
+	ReasonNoLower:     "nolower",
+	ReasonNoSymbol:    "nosymbol",
+	ReasonBreached:    "breached",
+	ReasonFewUnique:   "fewunique",
+	ReasonKeyboard:    "keyboard",
+	ReasonDenied:      "denied",
+	ReasonTruncated:   "truncated",
+	ReasonDate:        "date",
+}
+
+// String returns a short stable code for the reason, such as "short".
+func (r Reason) String() string {
+	if r >= 0 && int(r) < len(reasonNames) {
+		return reasonNames[r]
+	}
+	return "Reason(" + strconv.Itoa(int(r)) + ")"
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, so that
+// reasons are encoded as their codes, such as "short", in JSON and other
+// text formats. Reasons unknown to this package are encoded as "unknown".
+func (r Reason) MarshalText() ([]byte, error) {
+	if r < 0 || int(r) >= len(reasonNames) {
+		r = ReasonUnknown
+	}
+	return []byte(reasonNames[r]), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. It
+// accepts the codes returned by String.
+func (r *Reason) UnmarshalText(text []byte) error {
+	for i, name := range reasonNames {
+		if name == string(text) {
+			*r = Reason(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("passwordcheck: unknown reason %q", text)
+}
+
+// Error is an error returned by Check.
+//
+// Every returned error is either one of the Err* values or wraps one of
+// them, so errors.Is can be used to test for them. Errors for reasons
+// unknown to this package wrap ErrFailed. Messages are copied from
+// passwdqc into Go strings, so errors are safe to keep and pass across
+// goroutines or RPC boundaries after the check returns.
 type Error struct {
-	reason *C.char
-	desc   string
+	code Reason
+	desc string
+	err  error // wrapped error or nil
 }
 
 func (e *Error) Error() string {
 	return e.desc
 }
 
-var errorsByReason = make(map[*C.char]*Error)
+// Unwrap returns the wrapped error or nil.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Reason returns the reason code of the error.
+func (e *Error) Reason() Reason {
+	return e.code
+}
+
+// MarshalJSON implements the json.Marshaler interface. The error is
+// encoded as an object with the reason code and the message without the
+// package prefix, for example:
+//
+//	{"reason":"short","message":"too short"}
+//
+// Errors without a known reason have the reason "unknown".
+func (e *Error) MarshalJSON() ([]byte, error) {
+	reason := e.code
+	if reason <= ReasonNone || int(reason) >= len(reasonNames) {
+		reason = ReasonUnknown
+	}
+	return json.Marshal(struct {
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	}{reason.String(), strings.TrimPrefix(e.desc, "passwordcheck: ")})
+}
 
-func newError(reason *C.char) *Error {
-	e := &Error{reason, "passwordcheck: " + C.GoString(reason)}
+var (
+	allErrors      []*Error
+	errorsByReason = make(map[string]*Error)
+)
+
+// newError returns a new error for the passwdqc reason.
+func newError(code Reason, reason string) *Error {
+	e := newGoError(code, reason)
 	errorsByReason[reason] = e
 	return e
 }
 
+// newGoError returns a new error for reasons not known to passwdqc.
+func newGoError(code Reason, desc string) *Error {
+	return register(&Error{code: code, desc: "passwordcheck: " + desc})
+}
+
+// register adds the error to the errors returned by AllReasons.
+func register(e *Error) *Error {
+	allErrors = append(allErrors, e)
+	return e
+}
+
+// AllReasons returns errors for all known reasons in the order of their
+// reason codes. It can be used to enumerate the possible outcomes of Check.
+func AllReasons() []*Error {
+	return append([]*Error(nil), allErrors...)
+}
+
 var (
-	ErrEmpty       = errors.New("empty password")
-	ErrFailed      = newError(C.REASON_ERROR)       // check failed
-	ErrSame        = newError(C.REASON_SAME)        // same as the old one
-	ErrSimilar     = newError(C.REASON_SIMILAR)     // based on the old one
-	ErrShort       = newError(C.REASON_SHORT)       // too short
-	ErrLong        = newError(C.REASON_LONG)        // too long
-	ErrSimpleShort = newError(C.REASON_SIMPLESHORT) // not enough different characters or classes for this length
-	ErrSimple      = newError(C.REASON_SIMPLE)      // not enough different characters of classes
-	ErrPersonal    = newError(C.REASON_PERSONAL)    // based on user name
-	ErrWord        = newError(C.REASON_WORD)        // based on a directionary word and not a passphrase
-	ErrSeq         = newError(C.REASON_SEQ)         // based on a common sequence of characters and not a passphrase
+	// ErrEmpty is returned for empty passwords. Unlike other errors, its
+	// message, "empty password", has no package prefix, as in earlier
+	// versions, where it was created with errors.New; it's now an *Error
+	// with ReasonEmpty, so it can be compared with == as before.
+	ErrEmpty = register(&Error{code: ReasonEmpty, desc: "empty password"})
+
+	ErrFailed      = newError(ReasonFailed, reasonError)                                    // check failed
+	ErrSame        = newError(ReasonSame, reasonSame)                                       // same as the old one
+	ErrSimilar     = newError(ReasonSimilar, reasonSimilar)                                 // based on the old one
+	ErrShort       = newError(ReasonShort, reasonShort)                                     // too short
+	ErrLong        = newError(ReasonLong, reasonLong)                                       // too long
+	ErrSimpleShort = newError(ReasonSimpleShort, reasonSimpleShort)                         // not enough different characters or classes for this length
+	ErrSimple      = newError(ReasonSimple, reasonSimple)                                   // not enough different characters or classes
+	ErrPersonal    = newError(ReasonPersonal, reasonPersonal)                               // based on user name
+	ErrWord        = newError(ReasonWord, reasonWord)                                       // based on a dictionary word and not a passphrase
+	ErrSeq         = newError(ReasonSeq, reasonSeq)                                         // based on a common sequence of characters and not a passphrase
+	ErrNul         = newGoError(ReasonNul, "contains NUL byte")                             // contains NUL byte
+	ErrNoDigit     = newGoError(ReasonNoDigit, "must contain a digit")                      // no digits (RequireDigit)
+	ErrNoUpper     = newGoError(ReasonNoUpper, "must contain an upper-case letter")         // no upper-case letters (RequireUpper)
+	ErrNoLower     = newGoError(ReasonNoLower, "must contain a lower-case letter")          // no lower-case letters (RequireLower)
+	ErrNoSymbol    = newGoError(ReasonNoSymbol, "must contain a symbol")                    // no symbols (RequireSymbol)
+	ErrBreached    = newGoError(ReasonBreached, "found in a list of compromised passwords") // found in Blocklist
+	ErrFewUnique   = newGoError(ReasonFewUnique, "not enough unique characters")            // fewer than MinUnique different characters
+	ErrKeyboard    = newGoError(ReasonKeyboard, "based on a sequence of adjacent keys")     // keyboard walk (DenyKeyboardWalk)
+	ErrDenied      = newGoError(ReasonDenied, "matches a denied pattern")                   // matches DenyPatterns or a global blocklist term
+	ErrDate        = newGoError(ReasonDate, "based on a date")                              // mostly a date (DenyDateLike)
 )
 
+// ErrTruncated is the warning returned by TruncationWarning. It's not one
+// of the errors returned by Check and AllReasons.
+var ErrTruncated = &Error{code: ReasonTruncated, desc: "passwordcheck: only the first 8 characters will be used"}
+
 // Policy describes a password strength policy.
+//
+// Policies can't be compared with ==, since some fields, such as OnReject
+// and LeetMap, are functions or maps; compare the results of String or use
+// Diff instead.
 type Policy struct {
 	// Min declares the minimum allowed password lengths for different
 	// kinds of passwords and passphrases.
@@ -81,13 +258,35 @@ type Policy struct {
 	// Max is the maximum allowed password length.
 	//
 	// This can be used to prevent users from setting passwords that may be
-	// too long for some system services.
+	// too long for some system services. For example, bcrypt ignores bytes
+	// after the first 72, so with bcrypt, Max should be at most 72 to avoid
+	// silently accepting passwords that are weaker than they look.
+	//
+	// If Max is 0 or negative, there is no maximum length other than
+	// MaxPasswordLength. passwdqc.conf of upstream passwdqc has no such
+	// value; instead, passwdqc is given a maximum of math.MaxInt32, as
+	// shown by DebugParams, which no password reaches.
+	//
+	// Check rejects passwords longer than Max without copying them into C
+	// memory, unless Max is 8: in this case, as in passwdqc, longer
+	// passwords are not rejected, but truncated to 8 characters and then
+	// checked, and the new password is rejected with ErrSame if it starts
+	// with the old one. This is meant for the traditional DES-based crypt
+	// hashes, which only use the first 8 characters, and upstream warns
+	// users that their longer passwords will be truncated; use
+	// TruncationWarning to do the same.
 	Max int
 
 	// PassphraseWords is the number of words required for a passphrase.
 	// Set to 0 to disable the support for user-chosen passphrases.
 	PassphraseWords int
 
+	// PassphraseMinWordLen is the minimum length of words counted toward
+	// PassphraseWords, so that short words like "a" or "of" don't make
+	// a passphrase. The default, 0, counts words of any length, as
+	// passwdqc does.
+	PassphraseMinWordLen int
+
 	// MatchLength is the length of common substring required to conclude
 	// that a password is at least partially based on information found in
 	// a character string, or 0 to disable the substring search.
@@ -97,6 +296,13 @@ type Policy struct {
 	// requirements with the weak substring partially discounted.
 	MatchLength int
 
+	// SimilarMatchLength, if not 0, is used instead of MatchLength when
+	// comparing the new password with the old one for DenySimilar, so that
+	// a longer or shorter common substring can be required to consider the
+	// new password similar to the old one than to a dictionary word or the
+	// user name.
+	SimilarMatchLength int
+
 	// DenySimilar indicates whether a new password is allowed to be
 	// similar to the old one.
 	//
@@ -104,10 +310,305 @@ type Policy struct {
 	// sufficiently long common substring and the new password with the
 	// substring partially discounted would be weak.
 	DenySimilar bool
+
+	// DenyReversed indicates whether a new password is allowed to contain
+	// the old one reversed, such as "2drowssap" for "password2". passwdqc
+	// already treats such passwords as similar when they would be weak
+	// without the reversed part; if DenyReversed is set, they are rejected
+	// with ErrSimilar regardless of their strength. CaseSensitive and
+	// NoLeet apply to the comparison.
+	DenyReversed bool
+
+	// DenyShifted indicates whether a new password is allowed to be a
+	// simple variant of the old one: the same password with a different
+	// trailing number, such as "Password2" or "Password10" for
+	// "Password1", or the old password with every letter shifted by the
+	// same number of positions in the alphabet and every digit by the same
+	// number modulo 10, such as "Qbttxpse2" for "Password1". If
+	// DenyShifted is set, such passwords are rejected with ErrSimilar
+	// regardless of their strength. Letter case is ignored unless
+	// CaseSensitive is set.
+	//
+	// The heuristic may reject unrelated passwords which happen to match
+	// it, for example, random passwords differing only in trailing digits,
+	// but such coincidences are unlikely for passwords of reasonable
+	// length.
+	DenyShifted bool
+
+	// RandomBits is the number of bits of randomness in passphrases
+	// generated by the GenerateRandom method, from 24 to 136. Zero means
+	// the default, 47, as in passwdqc.
+	//
+	// As in passwdqc, RandomBits is only used for generating passphrases and
+	// doesn't affect checking: passwdqc doesn't estimate the randomness of
+	// passwords, but checks their length against Min for the number of
+	// character classes they use. The defaults are chosen so that generated
+	// passphrases of three words with 47 bits pass the checks of
+	// DefaultPolicy; with smaller Min[2] or PassphraseWords, passphrases
+	// with fewer bits may be accepted, too.
+	RandomBits int
+
+	// CaseSensitive indicates whether letter case is taken into account
+	// when matching substrings against the old password, user name, and
+	// dictionary words. By default, as in passwdqc, case is ignored.
+	CaseSensitive bool
+
+	// NoLeet indicates whether common character substitutions
+	// ("leetspeak"), such as '@' or '4' for 'a' and '0' for 'o', are
+	// kept when matching substrings. By default, as in passwdqc, they are
+	// undone, so that "p@ssw0rd" is treated like "password".
+	NoLeet bool
+
+	// LeetMap, unless NoLeet is set, adds substitutions to the built-in
+	// ones, which include '@' and '4' for 'a', '3' for 'e', '!' and '|'
+	// for 'i', '1' for 'l', '0' for 'o', '5' and '$' for 's', and '7' and
+	// '+' for 't', mapping substituted characters to letters, for example,
+	// '9' to 'g' and '(' to 'c'. Only ASCII characters are supported, and
+	// other entries are ignored. Since a substituted character is matched
+	// like the letter and all other characters substituted for it, mapping
+	// one character to different letters is not possible. NUL characters
+	// are not allowed.
+	LeetMap map[rune]rune
+
+	// ForbidUsername indicates whether passwords containing the user name,
+	// ignoring case, are rejected with ErrPersonal. Unlike the passwdqc
+	// check for personal information, which may accept such passwords if
+	// they are strong enough without the user name, this is a hard rule.
+	ForbidUsername bool
+
+	// SkipDictionary indicates whether passwdqc checks for passwords based
+	// on words from the built-in dictionary and on common sequences of
+	// characters are skipped, so that passwords are never rejected with
+	// ErrWord or ErrSeq by them. Length, character class, similarity, and
+	// personal information checks still apply, and so do Wordlist and
+	// DictionaryFile.
+	SkipDictionary bool
+
+	// HighEntropyBypass indicates whether the checks skipped by
+	// SkipDictionary are also skipped for passwords with at least 64 bits
+	// of randomness as estimated by Randomness, such as machine-generated
+	// tokens for service accounts, which may contain dictionary words or
+	// sequences by chance. Like EntropyOnly, it's only suitable for users
+	// who are expected to use random passwords.
+	HighEntropyBypass bool
+
+	// DenyKeyboardWalk indicates whether passwords that are mostly
+	// sequences of adjacent keys on a QWERTY keyboard, such as "qwerty",
+	// "asdfgh", or "1qaz2wsx", are rejected with ErrKeyboard. A password
+	// is considered a keyboard walk if it's at least 4 characters long
+	// and at least three quarters of its consecutive characters are on
+	// adjacent keys, ignoring letter case and Shift.
+	DenyKeyboardWalk bool
+
+	// DenyDateLike indicates whether passwords that are mostly dates, such
+	// as "01/02/1990", "1990-01-02", or "19900102!", are rejected with
+	// ErrDate. Dates in the ISO (year, month, day), US (month, day, year),
+	// and European (day, month, year) orders are detected, with "/", "-",
+	// ".", or space as separators, or without separators if the day and
+	// month have two digits. Years have two digits, or four digits from
+	// 1900 to 2099. A password is considered a date if a date takes at
+	// least three quarters of its length.
+	DenyDateLike bool
+
+	// DiscountTrailingDigits indicates whether digits at the end of the
+	// new password, such as "1", "123", or "2024", are ignored when
+	// passwdqc evaluates its strength, since people often append them to
+	// weak passwords, so that "Kx#mLq2024" is judged like "Kx#mLq". The
+	// rest of the password must satisfy the Min values on its own, so the
+	// digits don't count toward the length, and a password losing its only
+	// digits may use fewer character classes, which requires a greater
+	// length. Passwords consisting only of digits are evaluated as is.
+	// Other checks, including comparisons with the old password for
+	// ErrSame and ErrSimilar, see the whole password.
+	DiscountTrailingDigits bool
+
+	// NonASCIIAsLetters indicates whether non-ASCII characters are counted
+	// as letters when passwdqc determines the number of character classes
+	// in a password, and thus which of the Min values applies. Characters
+	// of the upper-case letter ranges of Latin-1, Latin Extended-A, Greek,
+	// and Cyrillic are counted as upper-case letters, and other non-ASCII
+	// characters, including CJK ones, as lower-case letters, one per
+	// character rather than per byte. By default, as in passwdqc, they are
+	// counted separately and only add a class in some cases.
+	NonASCIIAsLetters bool
+
+	// MaxSequence, if not 0, is the maximum length of runs of sequential
+	// letters or digits in passwords, ascending or descending, such as
+	// "abcdef", "54321", or, wrapping around, "xyzab" and "8901". Letter
+	// case is ignored. Passwords with longer runs are rejected with ErrSeq
+	// regardless of their strength, unlike with passwdqc's check for
+	// common sequences, which only rejects weak passwords.
+	MaxSequence int
+
+	// ConstantTimeSame indicates whether the new password is compared with
+	// the old one for equality, after conversion to the canonical form
+	// (see Canonical), using subtle.ConstantTimeCompare, so that the time
+	// taken doesn't reveal how much of the new password matches the old
+	// one. It protects against attackers who can submit new passwords for
+	// an account and measure response times to learn the old password.
+	// passwdqc's own equality comparisons, which use strcmp, are skipped
+	// then.
+	//
+	// Only the equality comparison is constant-time: passwdqc's similarity
+	// and other heuristics, which also use the old password, inherently
+	// take time depending on the passwords, and so do the checks of the
+	// password itself. Canonical forms of different lengths are compared
+	// in time depending only on their lengths.
+	ConstantTimeSame bool
+
+	// RequireDigit, RequireUpper, RequireLower, and RequireSymbol indicate
+	// whether passwords must contain at least one digit, upper-case
+	// letter, lower-case letter, and symbol, respectively. Symbols are
+	// ASCII characters other than letters and digits, including space.
+	// Non-ASCII characters don't satisfy any of these requirements.
+	//
+	// These requirements are checked before passwdqc checks and are in
+	// addition to them: passwords must still satisfy Min for the number of
+	// character classes they use. Passwords missing a required character
+	// are rejected with ErrNoDigit, ErrNoUpper, ErrNoLower, or ErrNoSymbol.
+	RequireDigit  bool
+	RequireUpper  bool
+	RequireLower  bool
+	RequireSymbol bool
+
+	// MinUnique is the minimum number of distinct characters (Unicode code
+	// points) in passwords, or 0 to disable the requirement. For example,
+	// with MinUnique of 3, "ababababab" is rejected with ErrFewUnique
+	// regardless of its length. Like the Require fields, it's checked
+	// before passwdqc checks.
+	MinUnique int
+
+	// EntropyOnly, if set, replaces all strength checks with a single
+	// requirement: passwords must have at least MinEntropyBits bits of
+	// randomness as estimated by Randomness, and are rejected with
+	// ErrSimple otherwise. Character class, dictionary, and personal
+	// information heuristics, as well as Require fields, MinUnique,
+	// Wordlist, and Blocklist, are not used; passwords longer than Max
+	// are still rejected with ErrLong, and those with the same canonical
+	// form as the old password with ErrSame.
+	//
+	// Since Randomness assumes that passwords are random, this mode is
+	// only suitable for users who are expected to choose random passwords.
+	EntropyOnly bool
+
+	// MinEntropyBits is the minimum number of bits of randomness required
+	// in EntropyOnly mode.
+	MinEntropyBits int
+
+	// Wordlist, if not nil, is a list of words in addition to the built-in
+	// dictionary, such as common or breached passwords. Passwords that
+	// contain a word from the list at least MatchLength characters long,
+	// ignoring case, and that would be too simple without it, are rejected
+	// with ErrWord.
+	Wordlist *Wordlist
+
+	// DictionaryFile, if not empty, is the name of a file with additional
+	// dictionary words, which are used like those of Wordlist, so that
+	// language-specific or domain-specific dictionaries can be used
+	// without recompiling. The file must contain one word per line, and
+	// it's gzip-compressed if its name ends with ".gz", like
+	// testdata/passwords.txt.gz. Empty lines are skipped.
+	//
+	// The file is read by NewPolicy and PolicyFromEnv if the name is set
+	// by the dictfile configuration item. Otherwise, for example, after
+	// ParsePolicy, GobDecode, or setting DictionaryFile directly,
+	// LoadDictionaryFile must be called to read it; until then, checks
+	// fail with ErrFailed.
+	DictionaryFile string
+
+	// MaxCheckDuration, if positive, limits the time a check spends
+	// scanning Wordlist, DictionaryFile, and the terms registered with
+	// RegisterGlobalBlocklist, so that large lists can't make checks slow
+	// enough to be used for denial of service. The time is measured from
+	// the start of the scanning, and the rest of the check, including
+	// passwdqc, Blocklist, and the built-in dictionary, is not limited.
+	//
+	// When the time is exceeded, the remaining words and terms are not
+	// checked. By default, this fails open: the password is accepted if
+	// it passes the rest of the check, even though it could contain an
+	// unchecked word. Set TimeoutFailClosed to reject it with ErrFailed
+	// instead, which is safer, but rejects good passwords under load.
+	MaxCheckDuration time.Duration
+
+	// TimeoutFailClosed indicates whether passwords are rejected with
+	// ErrFailed if MaxCheckDuration is exceeded.
+	TimeoutFailClosed bool
+
+	// DenyPatterns are regular expressions for passwords that are not
+	// allowed, such as passwords containing the current year or internal
+	// project names. Passwords matching any of them are rejected with
+	// ErrDenied. A pattern matches if it matches any part of the password;
+	// anchor it with ^ and $ to match the whole password, and use (?i) to
+	// ignore letter case.
+	DenyPatterns []*regexp.Regexp
+
+	// IgnoreGlobalBlocklist indicates whether the terms registered with
+	// RegisterGlobalBlocklist are not used by this policy.
+	IgnoreGlobalBlocklist bool
+
+	// Blocklist, if not nil, is a set of compromised passwords, such as
+	// BlocklistBloom. Passwords found in it are rejected with ErrBreached.
+	// If the blocklist returns an error, the password is rejected with
+	// ErrFailed.
+	Blocklist Blocklist
+
+	// OnReject, if not nil, is called with the reason whenever Check
+	// rejects a password. It may be called concurrently from multiple
+	// goroutines.
+	OnReject func(Reason)
+
+	// Logger, if not nil, receives a debug-level record for every password
+	// checked by Check and other methods calling OnReject, with the
+	// outcome and the reason code. Passwords, user names, and other
+	// checked data are never logged.
+	Logger *slog.Logger
+
+	// Stats, if not nil, counts the outcomes of Check and other methods
+	// calling OnReject by reason. Unlike OnReject, it's also updated for
+	// accepted passwords, with ReasonNone.
+	Stats *Stats
+
+	// dictionary, if not nil, holds the words read from DictionaryFile.
+	dictionary *dictionaryFile
+
+	// compiled, if not nil, holds passwdqc parameters precomputed by
+	// NewPolicy.
+	compiled *qcParams
 }
 
 // Disabled provides a value for Policy's Min to disable a password kind.
-var Disabled = C.INT_MAX
+var Disabled = math.MaxInt32
+
+// MaxPasswordLength is the maximum length of a password that can be checked.
+// Longer passwords are rejected with ErrLong regardless of policy. Old
+// passwords and user names longer than that are truncated to it before
+// comparing them with the new password.
+const MaxPasswordLength = 10000 // PASSWDQC_MAX_LENGTH in passwdqc.h
+
+// truncate returns b truncated to MaxPasswordLength bytes, so that long old
+// passwords and user names can't make comparisons slow.
+func truncate(b []byte) []byte {
+	if len(b) > MaxPasswordLength {
+		return b[:MaxPasswordLength]
+	}
+	return b
+}
+
+// PasswdqcVersion returns the version of upstream passwdqc that the bundled,
+// modified passwdqc code is based on, such as "1.3.0".
+func PasswdqcVersion() string {
+	return qcVersion()
+}
+
+// Init performs one-time initialization of the package, which runs the
+// passwdqc self-test described in Available, so that it doesn't slow down
+// the first check. It returns nil if passwdqc is available, or the error,
+// wrapping ErrFailed, that checks would return otherwise. Calling it is
+// optional: it is safe to call concurrently and more than once, and checks
+// work without it.
+func Init() error {
+	return qcReady()
+}
 
 // DefaultPolicy is the default password strength policy.
 var DefaultPolicy = &Policy{
@@ -116,6 +617,51 @@ var DefaultPolicy = &Policy{
 	PassphraseWords: 3,
 	MatchLength:     4,
 	DenySimilar:     true,
+	RandomBits:      randomBits,
+}
+
+// Passphrase can be passed to MinForClasses and SetMinForClasses instead
+// of the number of character classes to refer to the minimum length of
+// passphrases.
+const Passphrase = -1
+
+// minIndex are the indexes of Policy's Min for the numbers of character
+// classes.
+var minIndex = [...]int{1: 0, 2: 1, 3: 3, 4: 4}
+
+// minIndexFor returns the index of Policy's Min for n, which is the number
+// of character classes or Passphrase. It panics if n is out of range.
+func minIndexFor(n int) int {
+	switch {
+	case n == Passphrase:
+		return 2
+	case n >= 1 && n <= 4:
+		return minIndex[n]
+	}
+	panic(fmt.Sprintf("passwordcheck: invalid number of character classes: %d", n))
+}
+
+// MinForClasses returns the minimum length of passwords consisting of
+// characters from n character classes, from 1 to 4, or of passphrases if
+// n is Passphrase, which may be Disabled. It panics if n is out of range.
+func (p *Policy) MinForClasses(n int) int {
+	return p.Min[minIndexFor(n)]
+}
+
+// SetMinForClasses sets the minimum length of passwords consisting of
+// characters from n character classes, from 1 to 4, or of passphrases if
+// n is Passphrase, to length, which may be Disabled. It panics if n is out
+// of range. Parameters precomputed by NewPolicy are discarded, so the new
+// length takes effect for policies returned by it too.
+func (p *Policy) SetMinForClasses(n, length int) {
+	p.Min[minIndexFor(n)] = length
+	p.compiled = nil
+}
+
+// Check checks the new password with DefaultPolicy. It's a shortcut for
+// DefaultPolicy.Check(newPassword, oldPassword, username).
+func Check(newPassword, oldPassword, username []byte) error {
+	return DefaultPolicy.Check(newPassword, oldPassword, username)
 }
 
 // Check checks that the new password complies with the policy and returns nil
@@ -123,75 +669,1066 @@ var DefaultPolicy = &Policy{
 //
 // If old password or user name are not nil, the are also used for checking,
 // for example, to make sure that the new password sufficiently differs from
-// the old one.
+// the old one. Passwords with the same canonical form as the old one (see
+// Canonical) are rejected with ErrSame.
+//
+// If the new password is both similar to the old one and too short or too
+// simple, it's rejected with ErrSimilar rather than ErrShort,
+// ErrSimpleShort, or ErrSimple, so that callers can tell users that the
+// problem is the similarity. ErrSame takes precedence over ErrSimilar.
+//
+// Empty passwords and passwords consisting only of white space, as defined
+// by Unicode, are rejected with ErrEmpty. Passwords and user names
+// containing NUL bytes are rejected with ErrNul, since passwdqc would ignore
+// everything after the first NUL.
 func (p *Policy) Check(newPassword, oldPassword, username []byte) error {
-	if newPassword == nil {
+	return p.rejected(p.check(newPassword, oldPassword, username))
+}
+
+// rejected calls OnReject if err is not nil, logs the outcome to Logger,
+// counts it in Stats, and returns err.
+func (p *Policy) rejected(err error) error {
+	reason := ReasonNone
+	if err != nil {
+		reason = err.(*Error).Reason()
+	}
+	if err != nil && p.OnReject != nil {
+		p.OnReject(reason)
+	}
+	if p.Stats != nil {
+		p.Stats.add(reason)
+	}
+	if p.Logger != nil {
+		p.Logger.LogAttrs(context.Background(), slog.LevelDebug, "passwordcheck: password checked",
+			slog.Bool("accepted", err == nil), slog.String("reason", reason.String()))
+	}
+	return err
+}
+
+// CheckMigration checks the new password with the policy for migrating to
+// newPolicy: err is the result of Check with the policy, and, if the
+// password is accepted, warn is the error newPolicy would reject it with,
+// or nil if newPolicy accepts it too. This allows accepting passwords
+// under the current policy while asking users to choose ones that will
+// satisfy newPolicy before it's enforced. OnReject and Logger of newPolicy
+// are not used.
+func (p *Policy) CheckMigration(newPolicy *Policy, newPassword, oldPassword, username []byte) (warn, err error) {
+	if err = p.Check(newPassword, oldPassword, username); err != nil {
+		return nil, err
+	}
+	return newPolicy.check(newPassword, oldPassword, username), nil
+}
+
+// CheckWithEmail is like Check, but uses the local part of the email
+// address as the user name, and also rejects new passwords based on the
+// domain or the whole address with ErrPersonal.
+func (p *Policy) CheckWithEmail(newPassword, oldPassword []byte, email string) error {
+	if hasNul([]byte(email)) {
+		return p.rejected(ErrNul)
+	}
+	local, domain := email, ""
+	if i := strings.LastIndexByte(email, '@'); i >= 0 {
+		local, domain = email[:i], email[i+1:]
+	}
+	err := p.check(newPassword, oldPassword, []byte(local))
+	if err == nil {
+		for _, s := range []string{domain, email} {
+			if s != "" && p.basedOn(newPassword, s) {
+				err = ErrPersonal
+				break
+			}
+		}
+	}
+	return p.rejected(err)
+}
+
+func (p *Policy) check(newPassword, oldPassword, username []byte) error {
+	oldPassword, username = truncate(oldPassword), truncate(username)
+	if isBlank(newPassword) {
 		return ErrEmpty
 	}
-	np := C.CString(string(newPassword))
-	defer C.passwdqc_free(np)
-	var op, u *C.char
-	if oldPassword != nil {
-		op = C.CString(string(oldPassword))
-		defer C.passwdqc_free(op)
+	if hasNul(newPassword) || hasNul(oldPassword) || hasNul(username) {
+		return ErrNul
 	}
-	if username != nil {
-		u = C.CString(string(username))
-		defer C.passwdqc_free(u)
+	if len(newPassword) > MaxPasswordLength || p.tooLong(newPassword, oldPassword) {
+		return ErrLong
 	}
-	// Copy parameters.
-	var params C.passwdqc_params_qc_t
-	for i, v := range p.Min {
-		params.min[i] = C.int(v)
+	if p.EntropyOnly {
+		if errs := p.checkEntropy(newPassword, oldPassword, false); len(errs) > 0 {
+			return errs[0]
+		}
+		return nil
+	}
+	if p.sameCanonical(newPassword, oldPassword) {
+		return ErrSame
+	}
+	if errs := p.checkRequired(newPassword, false); len(errs) > 0 {
+		return errs[0]
+	}
+	if err := p.passwdqcCheck(newPassword, oldPassword, username); err != nil {
+		// passwdqc checks the length and simplicity of passwords before
+		// comparing them with the old one.
+		if (err == ErrShort || err == ErrSimpleShort || err == ErrSimple) && p.similarToOld(newPassword, oldPassword) {
+			return ErrSimilar
+		}
+		return err
 	}
-	params.max = C.int(p.Max)
-	params.passphrase_words = C.int(p.PassphraseWords)
-	params.match_length = C.int(p.MatchLength)
-	if p.DenySimilar {
-		params.similar_deny = 1
-	} else {
-		params.similar_deny = 0
+	if errs := p.checkRules(newPassword, oldPassword, username, false); len(errs) > 0 {
+		return errs[0]
 	}
+	return nil
+}
 
-	reason := C.passwdqc_check(&params, np, op, u)
-	if reason != nil {
-		if err, ok := errorsByReason[reason]; ok {
-			return err
+// CheckAll is like Check, but instead of returning the first reason to reject
+// the password, it performs all checks independently and returns errors for
+// all of them, or nil if the password complies with the policy. The errors
+// are in the order of their reason codes.
+//
+// CheckAll is more expensive than Check, which stops at the first failed
+// check.
+func (p *Policy) CheckAll(newPassword, oldPassword, username []byte) []error {
+	oldPassword, username = truncate(oldPassword), truncate(username)
+	if isBlank(newPassword) {
+		return []error{ErrEmpty}
+	}
+	if hasNul(newPassword) || hasNul(oldPassword) || hasNul(username) {
+		return []error{ErrNul}
+	}
+	if len(newPassword) > MaxPasswordLength {
+		return []error{ErrLong}
+	}
+	if p.EntropyOnly {
+		return p.checkEntropy(newPassword, oldPassword, true)
+	}
+	if err := qcReady(); err != nil {
+		return []error{err}
+	}
+	params := p.params()
+	checked, old := p.discountDigits(newPassword), oldPassword
+	if len(checked) < len(newPassword) {
+		// Compare the whole password with the old one.
+		old = nil
+	}
+	failed := qcCheckAll(&params, checked, old, username)
+	if params.skipSame && p.sameTruncated(checked, old) {
+		failed = failed&^failedSimilar | failedSame
+	}
+	if old == nil && p.Similar(newPassword, oldPassword) {
+		failed |= failedSimilar
+	}
+	if p.sameCanonical(newPassword, oldPassword) {
+		failed = failed&^failedSimilar | failedSame
+	}
+	if p.skipDictionary(newPassword) {
+		failed &^= failedWord | failedSeq
+	}
+	var errs []error
+	for _, f := range failedErrors {
+		if failed&f.flag != 0 {
+			errs = append(errs, f.err)
+		}
+	}
+	errs = append(errs, p.checkRequired(newPassword, true)...)
+	for _, err := range p.checkRules(newPassword, oldPassword, username, true) {
+		if !containsError(errs, err) {
+			errs = append(errs, err)
 		}
-		return &Error{reason, C.GoString(reason)}
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].(*Error).Reason() < errs[j].(*Error).Reason()
+	})
+	return errs
+}
+
+// failedErrors maps passwdqc_check_all results to errors.
+var failedErrors = []struct {
+	flag uint
+	err  *Error
+}{
+	{failedError, ErrFailed},
+	{failedSame, ErrSame},
+	{failedSimilar, ErrSimilar},
+	{failedShort, ErrShort},
+	{failedLong, ErrLong},
+	{failedSimpleShort, ErrSimpleShort},
+	{failedSimple, ErrSimple},
+	{failedPersonal, ErrPersonal},
+	{failedWord, ErrWord},
+	{failedSeq, ErrSeq},
+}
+
+func containsError(errs []error, err error) bool {
+	for _, e := range errs {
+		if e == err {
+			return true
+		}
+	}
+	return false
+}
+
+// passwdqcCheck checks the password with passwdqc.
+func (p *Policy) passwdqcCheck(newPassword, oldPassword, username []byte) error {
+	if err := qcReady(); err != nil {
+		return err
+	}
+	params := p.params()
+	checked, old := p.discountDigits(newPassword), oldPassword
+	discounted := len(checked) < len(newPassword)
+	if discounted {
+		// Compare the whole password with the old one.
+		old = nil
+	}
+	reason := qcCheck(&params, checked, old, username)
+	if params.skipSame && reason != reasonShort && reason != reasonLong && p.sameTruncated(checked, old) {
+		// passwdqc checks the length before truncating the password.
+		reason = reasonSame
+	}
+	if discounted {
+		switch reason {
+		case "", reasonPersonal, reasonWord, reasonSeq:
+			// passwdqc checks the similarity after the length and
+			// simplicity, and before the rest.
+			if p.Similar(newPassword, oldPassword) {
+				return ErrSimilar
+			}
+		}
+	}
+	if (reason == reasonWord || reason == reasonSeq) && p.skipDictionary(newPassword) {
+		// passwdqc checks for dictionary words and sequences last.
+		return nil
+	}
+	if reason != "" {
+		return errorForReason(reason)
 	}
 	return nil
 }
 
+// discountDigits returns the password without trailing digits if
+// DiscountTrailingDigits is set and the password doesn't consist only of
+// digits, or the password otherwise.
+func (p *Policy) discountDigits(password []byte) []byte {
+	if !p.DiscountTrailingDigits {
+		return password
+	}
+	i := len(password)
+	for i > 0 && isDigit(password[i-1]) {
+		i--
+	}
+	if i == 0 {
+		return password
+	}
+	return password[:i]
+}
+
+// highEntropyBits is the number of bits of randomness at which
+// HighEntropyBypass skips dictionary checks.
+const highEntropyBits = 64
+
+// skipDictionary reports whether passwdqc checks for dictionary words and
+// common sequences are skipped for the password.
+func (p *Policy) skipDictionary(password []byte) bool {
+	if p.SkipDictionary {
+		return true
+	}
+	if !p.HighEntropyBypass {
+		return false
+	}
+	st := p.stats(password)
+	return entropy(&st) >= highEntropyBits
+}
+
+// errorForReason returns the error for the passwdqc reason. Reasons are
+// looked up by their text, not by the address of the C string, so any
+// string equal to a known reason maps to its error. Unknown reasons get a
+// new Error with ReasonUnknown wrapping ErrFailed.
+func errorForReason(reason string) error {
+	if err, ok := errorsByReason[strings.TrimSpace(reason)]; ok {
+		return err
+	}
+	return &Error{
+		code: ReasonUnknown,
+		desc: "passwordcheck: " + reason,
+		err:  ErrFailed,
+	}
+}
+
+// checkRequired checks that the password contains the required characters
+// and enough unique characters, and returns errors for the failed
+// requirements. If all is false, it stops at the first failed one.
+func (p *Policy) checkRequired(password []byte, all bool) (errs []error) {
+	if p.MinUnique > 0 && uniqueRunes(password, p.MinUnique) < p.MinUnique {
+		errs = append(errs, ErrFewUnique)
+		if !all {
+			return
+		}
+	}
+	if !p.RequireDigit && !p.RequireUpper && !p.RequireLower && !p.RequireSymbol {
+		return
+	}
+	var digit, upper, lower, symbol bool
+	for _, c := range password {
+		switch {
+		case c >= '0' && c <= '9':
+			digit = true
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c < 0x80:
+			symbol = true
+		}
+	}
+	for _, r := range []struct {
+		required, present bool
+		err               error
+	}{
+		{p.RequireDigit, digit, ErrNoDigit},
+		{p.RequireUpper, upper, ErrNoUpper},
+		{p.RequireLower, lower, ErrNoLower},
+		{p.RequireSymbol, symbol, ErrNoSymbol},
+	} {
+		if r.required && !r.present {
+			errs = append(errs, r.err)
+			if !all {
+				break
+			}
+		}
+	}
+	return
+}
+
+// checkEntropy checks the password in EntropyOnly mode. If all is false, it
+// stops at the first failed check.
+func (p *Policy) checkEntropy(newPassword, oldPassword []byte, all bool) (errs []error) {
+	if len(newPassword) > p.max() {
+		errs = append(errs, ErrLong)
+		if !all {
+			return
+		}
+	}
+	if p.sameCanonical(newPassword, oldPassword) {
+		errs = append(errs, ErrSame)
+		if !all {
+			return
+		}
+	}
+	st := p.stats(newPassword)
+	if entropy(&st) < float64(p.MinEntropyBits) {
+		errs = append(errs, ErrSimple)
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].(*Error).Reason() < errs[j].(*Error).Reason()
+	})
+	return
+}
+
+// uniqueRunes returns the number of distinct runes in the password, counting
+// up to limit.
+func uniqueRunes(password []byte, limit int) int {
+	seen := make(map[rune]bool)
+	for _, r := range string(password) {
+		seen[r] = true
+		if len(seen) >= limit {
+			break
+		}
+	}
+	return len(seen)
+}
+
+// checkRules checks the password against the rules the policy enforces in
+// addition to passwdqc checks, and returns errors for the failed ones. If
+// all is false, it stops at the first failed rule.
+func (p *Policy) checkRules(newPassword, oldPassword, username []byte, all bool) (errs []error) {
+	if p.DenyReversed && p.containsReversed(newPassword, oldPassword) {
+		errs = append(errs, ErrSimilar)
+		if !all {
+			return
+		}
+	}
+	if p.DenyShifted && p.shifted(newPassword, oldPassword) {
+		errs = append(errs, ErrSimilar)
+		if !all {
+			return
+		}
+	}
+	if p.ForbidUsername && len(username) > 0 &&
+		bytes.Contains(bytes.ToLower(newPassword), bytes.ToLower(username)) {
+		errs = append(errs, ErrPersonal)
+		if !all {
+			return
+		}
+	}
+	if p.MaxSequence > 0 && longestSequence(newPassword) > p.MaxSequence {
+		errs = append(errs, ErrSeq)
+		if !all {
+			return
+		}
+	}
+	if p.DenyKeyboardWalk && qwerty.isWalk(newPassword) {
+		errs = append(errs, ErrKeyboard)
+		if !all {
+			return
+		}
+	}
+	if p.DenyDateLike && isDateLike(newPassword) {
+		errs = append(errs, ErrDate)
+		if !all {
+			return
+		}
+	}
+	d := newDeadline(p.MaxCheckDuration)
+	denied := false
+	for _, re := range p.DenyPatterns {
+		if re.Match(newPassword) {
+			denied = true
+			break
+		}
+	}
+	if !denied && !p.IgnoreGlobalBlocklist {
+		denied = containsGlobalTerm(newPassword, d)
+	}
+	if denied {
+		errs = append(errs, ErrDenied)
+		if !all {
+			return
+		}
+	}
+	if p.Wordlist != nil && p.basedOnWordlist(p.Wordlist, newPassword, d) {
+		errs = append(errs, ErrWord)
+		if !all {
+			return
+		}
+	}
+	if p.DictionaryFile != "" {
+		switch wl := p.dictionaryWords(); {
+		case wl == nil:
+			errs = append(errs, ErrFailed)
+			if !all {
+				return
+			}
+		case p.basedOnWordlist(wl, newPassword, d):
+			if !containsError(errs, ErrWord) {
+				errs = append(errs, ErrWord)
+			}
+			if !all {
+				return
+			}
+		}
+	}
+	if d.exceeded() && p.TimeoutFailClosed {
+		errs = append(errs, ErrFailed)
+		if !all {
+			return
+		}
+	}
+	if p.Blocklist != nil {
+		found, err := p.Blocklist.Contains(newPassword)
+		switch {
+		case err != nil:
+			errs = append(errs, ErrFailed)
+		case found:
+			errs = append(errs, ErrBreached)
+		}
+	}
+	return
+}
+
+// containsReversed reports whether the new password contains the old
+// password reversed, after unifying both as passwdqc does.
+func (p *Policy) containsReversed(newPassword, oldPassword []byte) bool {
+	if len(oldPassword) == 0 {
+		return false
+	}
+	params := p.params()
+	unified := make([]byte, len(newPassword))
+	for i, c := range newPassword {
+		unified[i] = params.unifyMap[c]
+	}
+	reversed := make([]byte, len(oldPassword))
+	for i, c := range oldPassword {
+		reversed[len(oldPassword)-1-i] = params.unifyMap[c]
+	}
+	return bytes.Contains(unified, reversed)
+}
+
+// similarToOld reports whether the new password would be rejected with
+// ErrSimilar because of DenySimilar, DenyReversed, or DenyShifted.
+func (p *Policy) similarToOld(newPassword, oldPassword []byte) bool {
+	return p.Similar(newPassword, oldPassword) ||
+		p.DenyReversed && p.containsReversed(newPassword, oldPassword) ||
+		p.DenyShifted && p.shifted(newPassword, oldPassword)
+}
+
+// shifted reports whether the new password is a variant of the old one as
+// described for DenyShifted.
+func (p *Policy) shifted(newPassword, oldPassword []byte) bool {
+	if len(oldPassword) == 0 || bytes.Equal(newPassword, oldPassword) {
+		return false
+	}
+	if !p.CaseSensitive {
+		newPassword, oldPassword = bytes.ToLower(newPassword), bytes.ToLower(oldPassword)
+	}
+	// Different trailing number.
+	np := bytes.TrimRight(newPassword, "0123456789")
+	op := bytes.TrimRight(oldPassword, "0123456789")
+	if len(np) > 0 && len(np) < len(newPassword) && len(op) < len(oldPassword) && bytes.Equal(np, op) {
+		return true
+	}
+	// Caesar shift of letters and digits.
+	if len(newPassword) != len(oldPassword) {
+		return false
+	}
+	letterShift, digitShift := -1, -1
+	for i, n := range newPassword {
+		o := oldPassword[i]
+		size, s := 26, &letterShift
+		switch {
+		case isDigit(n) && isDigit(o):
+			size, s = 10, &digitShift
+		case isLower(n) && isLower(o), isUpper(n) && isUpper(o):
+		case n == o:
+			continue
+		default:
+			return false
+		}
+		shift := (int(n) - int(o) + size) % size
+		if *s >= 0 && *s != shift {
+			return false
+		}
+		*s = shift
+	}
+	return letterShift > 0 || digitShift > 0
+}
+
+// longestSequence returns the length of the longest run of sequential
+// letters or digits in the password, as described for MaxSequence.
+func longestSequence(password []byte) int {
+	longest, run, dir := 0, 0, 0
+	for i, c := range password {
+		d := 0
+		if i > 0 {
+			d = sequenceStep(password[i-1], c)
+		}
+		switch {
+		case d != 0 && d == dir:
+			run++
+		case d != 0:
+			run, dir = 2, d
+		default:
+			run, dir = 1, 0
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+// sequenceStep returns 1 if b follows a in the alphabet or among digits,
+// -1 if a follows b, and 0 otherwise.
+func sequenceStep(a, b byte) int {
+	var n, size int
+	switch {
+	case isDigit(a) && isDigit(b):
+		n, size = int(b)-int(a), 10
+	case isAlpha(a) && isAlpha(b):
+		n, size = int(b|0x20)-int(a|0x20), 26
+	default:
+		return 0
+	}
+	switch (n + size) % size {
+	case 1:
+		return 1
+	case size - 1:
+		return -1
+	}
+	return 0
+}
+
+// Canonical returns the canonical form of the password, which is used to
+// compare the new password with the old one: passwords with the same
+// canonical form are rejected with ErrSame. In the canonical form, white
+// space at the beginning and at the end of the password is removed, and
+// unless CaseSensitive is set, letters are converted to lower case, so that
+// "Password1 " and "password1" are considered the same.
+//
+// Unicode normalization is not performed, since this package doesn't depend
+// on golang.org/x/text; if needed, normalize passwords, for example, with
+// norm.NFC before checking them.
+func (p *Policy) Canonical(password []byte) []byte {
+	c := bytes.TrimSpace(password)
+	if !p.CaseSensitive {
+		return bytes.ToLower(c)
+	}
+	return append([]byte(nil), c...)
+}
+
+// sameCanonical reports whether the new and old passwords have the same
+// canonical form. It returns false if the old password is nil.
+func (p *Policy) sameCanonical(newPassword, oldPassword []byte) bool {
+	return oldPassword != nil && p.equal(p.Canonical(newPassword), p.Canonical(oldPassword))
+}
+
+// sameTruncated reports whether passwdqc, which truncates new passwords
+// longer than 8 characters if Max is 8, would find the new password the same
+// as the old one after truncation. It replaces passwdqc's comparisons, which
+// are skipped if ConstantTimeSame is set.
+func (p *Policy) sameTruncated(newPassword, oldPassword []byte) bool {
+	return p.max() == 8 && len(newPassword) > 8 && len(oldPassword) >= 8 &&
+		p.equal(newPassword[:8], oldPassword[:8])
+}
+
+// equal reports whether a and b are equal, comparing them in constant time
+// if ConstantTimeSame is set.
+func (p *Policy) equal(a, b []byte) bool {
+	if p.ConstantTimeSame {
+		return subtle.ConstantTimeCompare(a, b) == 1
+	}
+	return bytes.Equal(a, b)
+}
+
+// isBlank reports whether b is empty or consists only of white space.
+func isBlank(b []byte) bool {
+	return len(bytes.TrimSpace(b)) == 0
+}
+
+// hasNul reports whether b contains a NUL byte.
+func hasNul(b []byte) bool {
+	return bytes.IndexByte(b, 0) >= 0
+}
+
+// IsSatisfiable reports whether any password can comply with the policy's
+// length requirements. It returns false if all passwords are either too
+// short or too long.
+//
+// Since passwdqc rejects all passwords shorter than Min[4], and accepts
+// passwords of this length using four character classes, the policy is
+// satisfiable if and only if Min[4] does not exceed the maximum length.
+func (p *Policy) IsSatisfiable() bool {
+	limit := p.max()
+	if limit > MaxPasswordLength {
+		limit = MaxPasswordLength
+	}
+	return p.Min[4] <= limit
+}
+
+// max returns the maximum allowed password length.
+func (p *Policy) max() int {
+	if p.Max <= 0 {
+		return Disabled
+	}
+	return p.Max
+}
+
+// TruncationWarning returns ErrTruncated if Max is 8 and the password is
+// longer, so that Check and DES-based crypt hashes only use its first 8
+// characters, and users can be warned about it, as pam_passwdqc does.
+// Otherwise, it returns nil.
+func (p *Policy) TruncationWarning(password []byte) error {
+	if p.max() == 8 && len(password) > 8 {
+		return ErrTruncated
+	}
+	return nil
+}
+
+// CheckNew is a shortcut for Check(password, nil, nil) for checking new
+// passwords without the old password or user name.
+func (p *Policy) CheckNew(password []byte) error {
+	return p.Check(password, nil, nil)
+}
+
+// StillValid reports whether a password accepted earlier, for example,
+// provided by the user at login, complies with the current policy, so
+// that users can be asked to change passwords that no longer meet the
+// requirements. It checks the password like Check with the user name and
+// without the old password, but doesn't call OnReject or log to Logger,
+// since the password is not being set.
+func (p *Policy) StillValid(password, username []byte) bool {
+	return p.check(password, nil, username) == nil
+}
+
+// CheckWithNames is like Check, but matches the new password against each
+// of the names, such as the login, display name, and aliases of the user,
+// as Check does against the user name, and rejects it with ErrPersonal if
+// it's based on any of them. Empty names are ignored.
+func (p *Policy) CheckWithNames(newPassword, oldPassword []byte, names ...string) error {
+	return p.rejected(p.checkWithNames(newPassword, oldPassword, names))
+}
+
+// CheckUsers is like CheckWithNames with the current and previous user
+// names, for accounts that have been renamed: the new password is rejected
+// with ErrPersonal if it's based on either of them. The previous name may
+// be empty.
+func (p *Policy) CheckUsers(newPassword, oldPassword []byte, currentName, previousName string) error {
+	return p.CheckWithNames(newPassword, oldPassword, currentName, previousName)
+}
+
+// checkWithNames is CheckWithNames without calling OnReject.
+func (p *Policy) checkWithNames(newPassword, oldPassword []byte, names []string) error {
+	var first []byte
+	for _, name := range names {
+		if hasNul([]byte(name)) {
+			return ErrNul
+		}
+		if first == nil && name != "" {
+			first = []byte(name)
+		}
+	}
+	err := p.check(newPassword, oldPassword, first)
+	if err == nil {
+		for _, name := range names {
+			if name != "" && p.basedOnName(newPassword, []byte(name)) {
+				err = ErrPersonal
+				break
+			}
+		}
+	}
+	return err
+}
+
+// basedOnName reports whether Check would reject the new password with
+// ErrPersonal for the user name.
+func (p *Policy) basedOnName(newPassword, name []byte) bool {
+	if p.ForbidUsername && bytes.Contains(bytes.ToLower(newPassword), bytes.ToLower(name)) {
+		return true
+	}
+	return p.basedOn(newPassword, string(name))
+}
+
+// CheckString is like Check, but takes strings. Empty old password and
+// user name are treated as absent, like nil slices are by Check.
+// Converting the strings costs little compared with the check itself, as
+// BenchmarkCheckBytes and BenchmarkCheckString show.
+func (p *Policy) CheckString(newPassword, oldPassword, username string) error {
+	return p.Check([]byte(newPassword), bytesOrNil(oldPassword), bytesOrNil(username))
+}
+
+// CheckUTF16 is like Check, but takes UTF-16 strings, such as password
+// buffers from Windows APIs, and converts them to UTF-8. Surrogate pairs
+// are decoded into single characters, and invalid sequences, such as
+// unpaired surrogates, are replaced with U+FFFD, the Unicode replacement
+// character, so they are checked as that character rather than rejected.
+// Nil old password and user name are treated as absent, like by Check.
+func (p *Policy) CheckUTF16(newPassword, oldPassword, username []uint16) error {
+	return p.Check(utf16Bytes(newPassword), utf16Bytes(oldPassword), utf16Bytes(username))
+}
+
+// utf16Bytes returns the UTF-16 string converted to UTF-8, or nil if s is
+// nil.
+func utf16Bytes(s []uint16) []byte {
+	if s == nil {
+		return nil
+	}
+	return []byte(string(utf16.Decode(s)))
+}
+
+// bytesOrNil returns the string as a byte slice, or nil if it's empty.
+func bytesOrNil(s string) []byte {
+	if s == "" {
+		return nil
+	}
+	return []byte(s)
+}
+
+// CheckAgainstHashes is like Check without the old password and user name,
+// but also rejects the new password with ErrSame if compare returns true
+// for it. The compare function should report whether the password matches
+// any of the stored hashes of previous passwords, for example, using
+// bcrypt, so that reuse can be prevented without keeping old passwords.
+//
+// Since comparing with hashes is usually slow, compare is only called for
+// passwords that pass the other checks.
+func (p *Policy) CheckAgainstHashes(newPassword []byte, compare func(candidate []byte) bool) error {
+	err := p.check(newPassword, nil, nil)
+	if err == nil && compare(newPassword) {
+		err = ErrSame
+	}
+	return p.rejected(err)
+}
+
+// FirstAcceptable checks candidate passwords in order and returns the index
+// of the first one that complies with the policy. If none do, it returns -1
+// and the error for the last candidate, or ErrEmpty if there are no
+// candidates.
+func (p *Policy) FirstAcceptable(candidates [][]byte, oldPassword, username []byte) (int, error) {
+	err := error(ErrEmpty)
+	for i, c := range candidates {
+		if err = p.Check(c, oldPassword, username); err == nil {
+			return i, nil
+		}
+	}
+	return -1, err
+}
+
+// dictionaryFile holds words read from the named dictionary file.
+type dictionaryFile struct {
+	name  string
+	words *Wordlist
+}
+
+// LoadDictionaryFile reads the words from DictionaryFile, so that they
+// are used by checks. It must be called for policies not created with
+// NewPolicy or PolicyFromEnv, such as after setting DictionaryFile directly
+// or parsing it with ParsePolicy. It does nothing if DictionaryFile is
+// empty.
+func (p *Policy) LoadDictionaryFile() error {
+	if err := p.loadDictionaryFile(); err != nil {
+		return fmt.Errorf("passwordcheck: error reading dictionary file: %w", err)
+	}
+	return nil
+}
+
+func (p *Policy) loadDictionaryFile() error {
+	p.dictionary = nil
+	if p.DictionaryFile == "" {
+		return nil
+	}
+	words, err := loadWordlistFile(p.DictionaryFile)
+	if err != nil {
+		return err
+	}
+	p.dictionary = &dictionaryFile{name: p.DictionaryFile, words: NewWordlist(words)}
+	return nil
+}
+
+// dictionaryWords returns the words read from DictionaryFile, or nil if
+// they haven't been read.
+func (p *Policy) dictionaryWords() *Wordlist {
+	if p.dictionary == nil || p.dictionary.name != p.DictionaryFile {
+		return nil
+	}
+	return p.dictionary.words
+}
+
+// CheckSameOnly is like Check, but uses the old password only to reject
+// the new password if it's the same as the old one with ErrSame, ignoring
+// DenySimilar, DenyReversed, and DenyShifted.
+func (p *Policy) CheckSameOnly(newPassword, oldPassword, username []byte) error {
+	q := *p
+	q.DenySimilar = false
+	q.DenyReversed = false
+	q.DenyShifted = false
+	q.compiled = nil
+	return q.Check(newPassword, oldPassword, username)
+}
+
+// tooLong reports whether passwdqc would reject the new password as too long.
+// It is used to reject long passwords before passing them to passwdqc.
+func (p *Policy) tooLong(newPassword, oldPassword []byte) bool {
+	if p.Max <= 0 || p.Max == 8 || len(newPassword) <= p.Max {
+		return false
+	}
+	// passwdqc checks for the same or too short password first.
+	return len(newPassword) >= p.Min[4] && !p.equal(newPassword, oldPassword)
+}
+
+// Similar reports whether the new password is based on the old one, that is,
+// whether Check would return ErrSimilar for it, without the rest of the
+// strength evaluation. It returns false if DenySimilar is not set.
+func (p *Policy) Similar(newPassword, oldPassword []byte) bool {
+	if !p.DenySimilar || len(newPassword) == 0 || oldPassword == nil {
+		return false
+	}
+	return p.basedOnOld(truncate(newPassword), truncate(oldPassword))
+}
+
+// PairSimilar reports whether either of the two passwords is based on the
+// other one, as Similar determines for the new and old passwords, or they
+// have the same canonical form, regardless of DenySimilar. It can be used
+// to audit password histories for trivial variations. It returns false if
+// either password is empty.
+func (p *Policy) PairSimilar(a, b []byte) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	a, b = truncate(a), truncate(b)
+	return p.sameCanonical(a, b) || p.basedOnOld(a, b) || p.basedOnOld(b, a)
+}
+
+// basedOnOld reports whether passwdqc considers the new password to be
+// based on the old one, using SimilarMatchLength.
+func (p *Policy) basedOnOld(newPassword, oldPassword []byte) bool {
+	params := p.params()
+	return qcBasedOn(params.similar(), newPassword, oldPassword)
+}
+
+// basedOn reports whether passwdqc considers the new password to be based
+// on the source string.
+func (p *Policy) basedOn(newPassword []byte, source string) bool {
+	params := p.params()
+	return qcBasedOn(&params, newPassword, []byte(source))
+}
+
+// params returns passwdqc parameters for the policy.
+func (p *Policy) params() (params qcParams) {
+	if p.compiled != nil {
+		return *p.compiled
+	}
+	for i, v := range p.Min {
+		params.min[i] = int32(v)
+	}
+	params.max = int32(p.max())
+	params.passphraseWords = int32(p.PassphraseWords)
+	params.passphraseMinWordLen = int32(p.PassphraseMinWordLen)
+	params.matchLength = int32(p.MatchLength)
+	params.similarMatchLength = int32(p.similarMatchLength())
+	params.similarDeny = p.DenySimilar
+	params.nonASCIILetters = p.NonASCIIAsLetters
+	params.skipSame = p.ConstantTimeSame
+	params.unifyMap = unifyMap(!p.CaseSensitive, !p.NoLeet)
+	if !p.NoLeet {
+		addLeet(&params.unifyMap, p.LeetMap)
+	}
+	return
+}
+
+// similarMatchLength returns SimilarMatchLength or MatchLength.
+func (p *Policy) similarMatchLength() int {
+	if p.SimilarMatchLength != 0 {
+		return p.SimilarMatchLength
+	}
+	return p.MatchLength
+}
+
+// addLeet adds the substitutions to the unify map, so that characters
+// substituted for letters are unified like the letters in the map before
+// the substitutions are added. Non-ASCII characters are ignored, since
+// passwdqc unifies bytes, and so is NUL, which terminates unified strings.
+func addLeet(m *[0x100]byte, substitutions map[rune]rune) {
+	base := *m
+	for sub, letter := range substitutions {
+		if sub > 0 && sub < 0x80 && letter > 0 && letter < 0x80 {
+			m[sub] = base[letter]
+		}
+	}
+}
+
+// DebugParams returns a description of the passwdqc parameters for the
+// policy as they are passed to the passwdqc implementation, for example:
+//
+//	min=[2147483647 24 11 8 7] max=1024 passphrase_words=3 passphrase_min_word_len=0 match_length=4 similar_match_length=4 similar_deny=1 non_ascii_letters=0 skip_same=0 unify_map=37
+//
+// where unify_map is the number of characters that are replaced when
+// matching substrings, unless CaseSensitive and NoLeet are set, including
+// those added by LeetMap. It is intended for debugging and its format may
+// change.
+func (p *Policy) DebugParams() string {
+	params := p.params()
+	r := qcResolvedParams(&params)
+	similar, letters, skipSame, unified := 0, 0, 0, 0
+	if r.similarDeny {
+		similar = 1
+	}
+	if r.nonASCIILetters {
+		letters = 1
+	}
+	if r.skipSame {
+		skipSame = 1
+	}
+	for i, c := range r.unifyMap {
+		if int(c) != i {
+			unified++
+		}
+	}
+	return fmt.Sprintf("min=%v max=%d passphrase_words=%d passphrase_min_word_len=%d match_length=%d similar_match_length=%d similar_deny=%d non_ascii_letters=%d skip_same=%d unify_map=%d",
+		r.min, r.max, r.passphraseWords, r.passphraseMinWordLen, r.matchLength, r.similarMatchLength, similar, letters, skipSame, unified)
+}
+
 // ParsePolicy parses a string describing password policy.
 // The format is similar to passwdqc, but a bit relaxed:
 //
-//  min=N0,N1,N2,N3,N4        default: min=disabled,24,11,8,7
-//  max=N                     default: max=40
-//  passphrase=N              default: passphrase=3
-//  match=N                   default: match=4
-//  similar=permit|deny       default: similar=deny
+//	min=N0,N1,N2,N3,N4        default: min=disabled,24,11,8,7
+//	max=N|unlimited           default: max=1024
+//	passphrase=N              default: passphrase=3
+//	wordlen=N                 default: wordlen=0
+//	unique=N                  default: unique=0
+//	sequence=N                default: sequence=0
+//	match=N                   default: match=4
+//	similarmatch=N            default: similarmatch=0
+//	similar=permit|deny       default: similar=deny
+//	reversed=permit|deny      default: reversed=permit
+//	shifted=permit|deny       default: shifted=permit
+//	random=N                  default: random=47
+//	case=ignore|match         default: case=ignore
+//	leet=match|ignore         default: leet=match
+//	username=permit|deny      default: username=permit
+//	dictionary=check|skip     default: dictionary=check
+//	highentropy=check|bypass  default: highentropy=check
+//	keyboard=permit|deny      default: keyboard=permit
+//	nonascii=other|letters    default: nonascii=other
+//	dictfile=FILE|none        default: dictfile=none
+//	mode=passwdqc|entropy     default: mode=passwdqc
+//	entropy=N                 default: entropy=0
+//	require=C1,C2,...|none    default: require=none
+//
+// Configuration items can be separated by any amount of white space,
+// including new lines and tabs, for example:
 //
-// Configuration items can be separated by a new line or by space,
-// for example:
+//	min=disabled,16,17,18,19 max=20 passphrase=21 match=22 similar=deny
 //
-//  min=disabled,16,17,18,19 max=20 passphrase=21 match=22 similar=deny
+// Both max=0 and max=unlimited mean that there is no maximum length.
+// Items wordlen, similarmatch, unique, sequence, and random correspond to
+// PassphraseMinWordLen, SimilarMatchLength, MinUnique, MaxSequence, and
+// RandomBits.
+// Items reversed, shifted, case, leet, username, dictionary, keyboard, and
+// date correspond to DenyReversed, DenyShifted, CaseSensitive (case=match),
+// NoLeet (leet=ignore), ForbidUsername, SkipDictionary, DenyKeyboardWalk,
+// and DenyDateLike fields of Policy.
+// Item nonascii=letters sets NonASCIIAsLetters, item highentropy=bypass
+// sets HighEntropyBypass, and item trailingdigits=discount sets
+// DiscountTrailingDigits.
+// Item dictfile sets DictionaryFile, which is not read by ParsePolicy; use
+// NewPolicy or call LoadDictionaryFile. File names containing white space
+// are not supported.
+// Item mode=entropy sets EntropyOnly, and item entropy sets MinEntropyBits.
+// Item require lists the required characters, which can be digit, upper,
+// lower, and symbol, corresponding to RequireDigit, RequireUpper,
+// RequireLower, and RequireSymbol.
 //
 // The order of items is not important.
 // There must be no spaces or excess commas between min values.
 // Items not present in the string are filled from DefaultPolicy.
+// If an item is present more than once, the last value is used; use
+// ParsePolicyStrict to reject such configurations.
 func ParsePolicy(config string) (p *Policy, err error) {
+	return parsePolicy(config, false)
+}
+
+// ParsePolicyStrict is like ParsePolicy, but returns DuplicateItemError
+// if an item is present more than once, such as in "max=10 max=20", which
+// usually indicates a mistake in the configuration.
+func ParsePolicyStrict(config string) (*Policy, error) {
+	return parsePolicy(config, true)
+}
+
+// DuplicateItemError is returned by ParsePolicyStrict for configuration
+// items that are present more than once.
+type DuplicateItemError struct {
+	Name string // name of the item, such as "max"
+}
+
+func (e *DuplicateItemError) Error() string {
+	return fmt.Sprintf("duplicate item: %q", e.Name)
+}
+
+func parsePolicy(config string, strict bool) (p *Policy, err error) {
 	p = new(Policy)
 	*p = *DefaultPolicy
-	config = strings.Replace(config, "\r\n", " ", -1)
-	config = strings.Replace(config, "\n", " ", -1)
-	items := strings.Split(config, " ")
+	items := strings.Fields(config)
+	if len(items) == 0 {
+		return nil, errors.New("empty config")
+	}
+	seen := make(map[string]bool)
 	for _, it := range items {
-		nameValue := strings.SplitN(strings.TrimSpace(it), "=", 2)
+		nameValue := strings.SplitN(it, "=", 2)
 		if len(nameValue) != 2 {
 			return nil, fmt.Errorf("error parsing item: %q", it)
 		}
 		name, value := nameValue[0], nameValue[1]
+		if strict && seen[name] {
+			return nil, &DuplicateItemError{Name: name}
+		}
+		seen[name] = true
 		switch name {
 		case "min":
 			vals := strings.Split(value, ",")
@@ -209,6 +1746,10 @@ func ParsePolicy(config string) (p *Policy, err error) {
 				}
 			}
 		case "max":
+			if value == "unlimited" {
+				p.Max = 0
+				break
+			}
 			p.Max, err = strconv.Atoi(value)
 			if err != nil {
 				return nil, fmt.Errorf("error parsing item: %q (%s)", it, err)
@@ -218,19 +1759,135 @@ func ParsePolicy(config string) (p *Policy, err error) {
 			if err != nil {
 				return nil, fmt.Errorf("error parsing item: %q (%s)", it, err)
 			}
+		case "wordlen":
+			p.PassphraseMinWordLen, err = strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing item: %q (%s)", it, err)
+			}
+		case "unique":
+			p.MinUnique, err = strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing item: %q (%s)", it, err)
+			}
+		case "sequence":
+			p.MaxSequence, err = strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing item: %q (%s)", it, err)
+			}
 		case "match":
 			p.MatchLength, err = strconv.Atoi(value)
 			if err != nil {
 				return nil, fmt.Errorf("error parsing item: %q (%s)", it, err)
 			}
+		case "similarmatch":
+			p.SimilarMatchLength, err = strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing item: %q (%s)", it, err)
+			}
 		case "similar":
-			switch value {
-			case "deny":
-				p.DenySimilar = true
-			case "permit":
-				p.DenySimilar = false
-			default:
-				return nil, fmt.Errorf("error parsing item: %q (unknown value %q)", it, value)
+			p.DenySimilar, err = parseChoice(it, value, "deny", "permit")
+			if err != nil {
+				return nil, err
+			}
+		case "case":
+			p.CaseSensitive, err = parseChoice(it, value, "match", "ignore")
+			if err != nil {
+				return nil, err
+			}
+		case "leet":
+			p.NoLeet, err = parseChoice(it, value, "ignore", "match")
+			if err != nil {
+				return nil, err
+			}
+		case "random":
+			p.RandomBits, err = strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing item: %q (%s)", it, err)
+			}
+			if p.RandomBits < minRandomBits || p.RandomBits > maxRandomBits {
+				return nil, fmt.Errorf("error parsing item: %q (must be from %d to %d)", it, minRandomBits, maxRandomBits)
+			}
+		case "reversed":
+			p.DenyReversed, err = parseChoice(it, value, "deny", "permit")
+			if err != nil {
+				return nil, err
+			}
+		case "shifted":
+			p.DenyShifted, err = parseChoice(it, value, "deny", "permit")
+			if err != nil {
+				return nil, err
+			}
+		case "username":
+			p.ForbidUsername, err = parseChoice(it, value, "deny", "permit")
+			if err != nil {
+				return nil, err
+			}
+		case "mode":
+			p.EntropyOnly, err = parseChoice(it, value, "entropy", "passwdqc")
+			if err != nil {
+				return nil, err
+			}
+		case "entropy":
+			p.MinEntropyBits, err = strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing item: %q (%s)", it, err)
+			}
+		case "keyboard":
+			p.DenyKeyboardWalk, err = parseChoice(it, value, "deny", "permit")
+			if err != nil {
+				return nil, err
+			}
+		case "date":
+			p.DenyDateLike, err = parseChoice(it, value, "deny", "permit")
+			if err != nil {
+				return nil, err
+			}
+		case "trailingdigits":
+			p.DiscountTrailingDigits, err = parseChoice(it, value, "discount", "count")
+			if err != nil {
+				return nil, err
+			}
+		case "dictionary":
+			p.SkipDictionary, err = parseChoice(it, value, "skip", "check")
+			if err != nil {
+				return nil, err
+			}
+		case "highentropy":
+			p.HighEntropyBypass, err = parseChoice(it, value, "bypass", "check")
+			if err != nil {
+				return nil, err
+			}
+		case "nonascii":
+			p.NonASCIIAsLetters, err = parseChoice(it, value, "letters", "other")
+			if err != nil {
+				return nil, err
+			}
+		case "dictfile":
+			if value == "" {
+				return nil, fmt.Errorf("error parsing item: %q (expected file name or none)", it)
+			}
+			p.DictionaryFile = value
+			if value == "none" {
+				p.DictionaryFile = ""
+			}
+		case "require":
+			p.RequireDigit, p.RequireUpper, p.RequireLower, p.RequireSymbol = false, false, false, false
+			if value == "none" {
+				break
+			}
+			for _, v := range strings.Split(value, ",") {
+				switch v {
+				case "digit":
+					p.RequireDigit = true
+				case "upper":
+					p.RequireUpper = true
+				case "lower":
+					p.RequireLower = true
+				case "symbol":
+					p.RequireSymbol = true
+				default:
+					return nil, fmt.Errorf("error parsing item: %q (unknown value %q)", it, v)
+				}
 			}
 		default:
 			return nil, fmt.Errorf("unrecognized name: %q", name)
@@ -238,3 +1895,252 @@ func ParsePolicy(config string) (p *Policy, err error) {
 	}
 	return p, nil
 }
+
+// NewPolicy parses the config in the format accepted by ParsePolicy,
+// validates the resulting policy, reads DictionaryFile, and precomputes
+// passwdqc parameters, so that checking passwords with it doesn't have to
+// build them on every call.
+// It is the preferred way to create a policy that is used many times, for
+// example, by a server.
+//
+// Fields of the returned policy affecting passwdqc parameters must not be
+// modified; use ParsePolicy to get a policy that can be changed.
+func NewPolicy(config string) (*Policy, error) {
+	p, err := ParsePolicy(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if err := p.LoadDictionaryFile(); err != nil {
+		return nil, err
+	}
+	params := p.params()
+	p.compiled = &params
+	return p, nil
+}
+
+// Validate checks that the policy values are within the ranges accepted by
+// passwdqc and that the policy accepts some passwords. It returns nil if
+// the policy is valid.
+func (p *Policy) Validate() error {
+	for sub, letter := range p.LeetMap {
+		if sub == 0 || letter == 0 {
+			return errors.New("passwordcheck: invalid policy: NUL character in LeetMap")
+		}
+	}
+	for i, v := range p.Min {
+		if v < 0 {
+			return fmt.Errorf("passwordcheck: invalid policy: negative min value %d", v)
+		}
+		if i > 0 && v > p.Min[i-1] {
+			return errors.New("passwordcheck: invalid policy: min values must be non-increasing")
+		}
+	}
+	if p.PassphraseWords < 0 || p.PassphraseMinWordLen < 0 || p.MatchLength < 0 || p.SimilarMatchLength < 0 || p.MinUnique < 0 || p.MaxSequence < 0 {
+		return errors.New("passwordcheck: invalid policy: negative passphrase, wordlen, match, similarmatch, unique, or sequence value")
+	}
+	if p.RandomBits != 0 && (p.RandomBits < minRandomBits || p.RandomBits > maxRandomBits) {
+		return fmt.Errorf("passwordcheck: invalid policy: random bits must be from %d to %d", minRandomBits, maxRandomBits)
+	}
+	if p.MinEntropyBits < 0 || p.EntropyOnly && p.MinEntropyBits == 0 {
+		return errors.New("passwordcheck: invalid policy: entropy mode requires positive entropy bits")
+	}
+	if !p.EntropyOnly && !p.IsSatisfiable() {
+		return errors.New("passwordcheck: invalid policy: no passwords satisfy length requirements")
+	}
+	if max := p.max(); !p.EntropyOnly && p.PassphraseWords > 0 && p.Min[2] != Disabled && max != Disabled {
+		if p.Min[2] > max {
+			return fmt.Errorf("passwordcheck: invalid policy: passphrases must be at least %d characters long (Min[2]), but Max is %d", p.Min[2], max)
+		}
+		wordLen := p.PassphraseMinWordLen
+		if wordLen < 1 {
+			wordLen = 1
+		}
+		// Words must be separated by at least one character.
+		if n := p.PassphraseWords*(wordLen+1) - 1; n > max {
+			return fmt.Errorf("passwordcheck: invalid policy: passphrases of %d words (PassphraseWords) at least %d characters long (PassphraseMinWordLen) are at least %d characters long, but Max is %d", p.PassphraseWords, wordLen, n, max)
+		}
+	}
+	return nil
+}
+
+// parseChoice parses the value of configuration item it, which must be either
+// yes or no, and returns true for yes.
+func parseChoice(it, value, yes, no string) (bool, error) {
+	switch value {
+	case yes:
+		return true, nil
+	case no:
+		return false, nil
+	}
+	return false, fmt.Errorf("error parsing item: %q (unknown value %q)", it, value)
+}
+
+// MustParsePolicy is like ParsePolicy but panics if the config cannot be
+// parsed. It simplifies safe initialization of global variables holding
+// policies.
+func MustParsePolicy(config string) *Policy {
+	p, err := ParsePolicy(config)
+	if err != nil {
+		panic("passwordcheck: ParsePolicy(" + strconv.Quote(config) + "): " + err.Error())
+	}
+	return p
+}
+
+// PolicyFromEnv returns a policy built from environment variables named
+// after configuration items accepted by ParsePolicy in upper case with the
+// given prefix and an underscore, such as PREFIX_MIN, PREFIX_MAX,
+// PREFIX_PASSPHRASE, PREFIX_MATCH, and PREFIX_SIMILAR. Values have the same
+// format as in ParsePolicy, for example, PREFIX_MIN=disabled,24,11,8,7.
+//
+// Items for unset or empty variables are filled from DefaultPolicy.
+// Errors name the variable that could not be parsed. If PREFIX_DICTFILE is
+// set, the dictionary file is read.
+func PolicyFromEnv(prefix string) (*Policy, error) {
+	var items []string
+	for _, it := range DefaultPolicy.configItems() {
+		name := prefix + "_" + strings.ToUpper(it.name)
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		item := it.name + "=" + value
+		if _, err := ParsePolicy(item); err != nil {
+			return nil, fmt.Errorf("error parsing environment variable %s: %w", name, err)
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		p := new(Policy)
+		*p = *DefaultPolicy
+		return p, nil
+	}
+	p, err := ParsePolicy(strings.Join(items, " "))
+	if err != nil {
+		return nil, err
+	}
+	if err := p.LoadDictionaryFile(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// String returns the policy in the format accepted by ParsePolicy.
+func (p *Policy) String() string {
+	items := p.configItems()
+	s := make([]string, len(items))
+	for i, it := range items {
+		s[i] = it.name + "=" + it.value
+	}
+	return strings.Join(s, " ")
+}
+
+// Diff returns lines describing changes from p to other, such as
+// "max: 1024 -> 512" or "similar: deny -> permit", with configuration
+// items named as in ParsePolicy. It returns an empty slice for policies
+// with equal configurations. Fields that cannot be represented in the
+// configuration, such as Wordlist or OnReject, are not compared.
+func (p *Policy) Diff(other *Policy) []string {
+	a, b := p.configItems(), other.configItems()
+	changes := []string{}
+	for i := range a {
+		if a[i].value != b[i].value {
+			changes = append(changes, a[i].name+": "+a[i].value+" -> "+b[i].value)
+		}
+	}
+	return changes
+}
+
+type configItem struct {
+	name, value string
+}
+
+// configItems returns configuration items describing the policy.
+func (p *Policy) configItems() []configItem {
+	min := make([]string, len(p.Min))
+	for i, v := range p.Min {
+		if v == Disabled {
+			min[i] = "disabled"
+		} else {
+			min[i] = strconv.Itoa(v)
+		}
+	}
+	max := strconv.Itoa(p.Max)
+	if p.Max == 0 {
+		max = "unlimited"
+	}
+	var require []string
+	if p.RequireDigit {
+		require = append(require, "digit")
+	}
+	if p.RequireUpper {
+		require = append(require, "upper")
+	}
+	if p.RequireLower {
+		require = append(require, "lower")
+	}
+	if p.RequireSymbol {
+		require = append(require, "symbol")
+	}
+	if len(require) == 0 {
+		require = append(require, "none")
+	}
+	return []configItem{
+		{"min", strings.Join(min, ",")},
+		{"max", max},
+		{"passphrase", strconv.Itoa(p.PassphraseWords)},
+		{"wordlen", strconv.Itoa(p.PassphraseMinWordLen)},
+		{"unique", strconv.Itoa(p.MinUnique)},
+		{"sequence", strconv.Itoa(p.MaxSequence)},
+		{"match", strconv.Itoa(p.MatchLength)},
+		{"similarmatch", strconv.Itoa(p.SimilarMatchLength)},
+		{"similar", choice(p.DenySimilar, "deny", "permit")},
+		{"reversed", choice(p.DenyReversed, "deny", "permit")},
+		{"shifted", choice(p.DenyShifted, "deny", "permit")},
+		{"random", strconv.Itoa(p.randomBits())},
+		{"case", choice(p.CaseSensitive, "match", "ignore")},
+		{"leet", choice(p.NoLeet, "ignore", "match")},
+		{"username", choice(p.ForbidUsername, "deny", "permit")},
+		{"dictionary", choice(p.SkipDictionary, "skip", "check")},
+		{"highentropy", choice(p.HighEntropyBypass, "bypass", "check")},
+		{"keyboard", choice(p.DenyKeyboardWalk, "deny", "permit")},
+		{"date", choice(p.DenyDateLike, "deny", "permit")},
+		{"trailingdigits", choice(p.DiscountTrailingDigits, "discount", "count")},
+		{"nonascii", choice(p.NonASCIIAsLetters, "letters", "other")},
+		{"dictfile", choice(p.DictionaryFile == "", "none", p.DictionaryFile)},
+		{"mode", choice(p.EntropyOnly, "entropy", "passwdqc")},
+		{"entropy", strconv.Itoa(p.MinEntropyBits)},
+		{"require", strings.Join(require, ",")},
+	}
+}
+
+// choice returns yes if v is true, and no otherwise.
+func choice(v bool, yes, no string) string {
+	if v {
+		return yes
+	}
+	return no
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+//
+// The policy is encoded in the format returned by String, so fields that
+// cannot be represented in it, such as OnReject, are not encoded.
+func (p *Policy) GobEncode() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// GobDecode implements the gob.GobDecoder interface.
+//
+// Like ParsePolicy, it doesn't read DictionaryFile, so decoding untrusted
+// data never opens files; call LoadDictionaryFile to read it.
+func (p *Policy) GobDecode(data []byte) error {
+	q, err := ParsePolicy(string(data))
+	if err != nil {
+		return err
+	}
+	*p = *q
+	return nil
+}
@@ -4,11 +4,23 @@
 package passwordcheck
 
 import (
-	"bufio"
+	"bytes"
 	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
 	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unicode"
+	"unicode/utf16"
 )
 
 func TestCheck(t *testing.T) {
@@ -32,6 +44,24 @@ func TestCheckNil(t *testing.T) {
 	}
 }
 
+func TestCheckNew(t *testing.T) {
+	if err := DefaultPolicy.CheckNew(nil); err != ErrEmpty {
+		t.Errorf("expected ErrEmpty for nil, got %v", err)
+	}
+	if err := DefaultPolicy.CheckNew([]byte{}); err != ErrEmpty {
+		t.Errorf("expected ErrEmpty for empty password, got %v", err)
+	}
+	if s := ErrEmpty.Error(); s != "empty password" {
+		t.Errorf("unexpected ErrEmpty message %q", s)
+	}
+	if err := DefaultPolicy.CheckNew([]byte("pass")); err != ErrShort {
+		t.Errorf("expected ErrShort, got %v", err)
+	}
+	if err := DefaultPolicy.CheckNew([]byte("dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU")); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+}
+
 func TestDisabled(t *testing.T) {
 	pass := []byte("pwrjysrgylwwajk")
 	pol := *DefaultPolicy
@@ -63,110 +93,1817 @@ func TestErrReturn(t *testing.T) {
 	}
 }
 
-func checkPasswordsFromFile(t *testing.T, filename string) {
-	fmt.Printf("[INFO] Checking common passwords from %s\n", filename)
-	f, err := os.Open(filename)
-	if err != nil {
-		t.Fatal(err)
+func TestNoLeet(t *testing.T) {
+	vectors := []string{
+		"p@ssw0rd",
+		"dr@gon99",
+		"s3cr3t!!",
+		"l0v3ly#1",
 	}
-	defer f.Close()
-	z, err := gzip.NewReader(f)
-	defer z.Close()
-	scanner := bufio.NewScanner(z)
-	numRead := 0
-	numRejected := 0
-	rejections := make(map[error]int)
-	for scanner.Scan() {
-		pw := scanner.Text()
-		if r := DefaultPolicy.Check([]byte(pw), nil, nil); r != nil {
-			rejections[r]++
-			numRejected++
-		} else {
-			fmt.Printf("[INFO] Accepted password: %q\n", pw)
+	pol := *DefaultPolicy
+	for i, v := range vectors {
+		pol.NoLeet = false
+		if err := pol.Check([]byte(v), nil, nil); err != ErrWord {
+			t.Errorf("%d: expected ErrWord for %q, got %v", i, v, err)
+		}
+		pol.NoLeet = true
+		if err := pol.Check([]byte(v), nil, nil); err != nil {
+			t.Errorf("%d: no error expected for %q without leet matching, got %v", i, v, err)
 		}
-		numRead++
 	}
-	if err := scanner.Err(); err != nil {
-		t.Fatal(err)
+}
+
+func TestLiteralPolicyUnifies(t *testing.T) {
+	// Policies created without DefaultPolicy ignore case and undo leetspeak,
+	// as passwdqc does.
+	pol := &Policy{Min: [5]int{Disabled, 24, 11, 8, 7}, Max: 40, PassphraseWords: 3, MatchLength: 4}
+	for _, v := range []string{"p@ssw0rd", "DR@GON99"} {
+		if err := pol.CheckString(v, "", ""); err != ErrWord {
+			t.Errorf("%q: expected ErrWord, got %v", v, err)
+		}
 	}
-	for k, v := range rejections {
-		fmt.Printf("[INFO] %d passwords: %s\n", v, k)
+	if a, b := pol.params().unifyMap, DefaultPolicy.params().unifyMap; a != b {
+		t.Error("unify map differs from DefaultPolicy")
 	}
-	fmt.Printf("[INFO] Rejected %d of %d passwords\n", numRejected, numRead)
 }
 
-func TestCommonPasswords(t *testing.T) {
-	checkPasswordsFromFile(t, "testdata/passwords.txt.gz")
+func TestLeetMap(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.LeetMap = map[rune]rune{'9': 'g', '(': 'c', '\u20ac': 'e'}
+	for _, v := range []string{"dra9on#1", "Dra9on#1"} {
+		if err := DefaultPolicy.CheckString(v, "", ""); err != nil {
+			t.Errorf("%q: no error expected without LeetMap, got %v", v, err)
+		}
+		if err := pol.CheckString(v, "", ""); err != ErrWord {
+			t.Errorf("%q: expected ErrWord, got %v", v, err)
+		}
+	}
+	if s := pol.DebugParams(); !strings.HasSuffix(s, " unify_map=39") {
+		t.Errorf("unexpected params: %s", s)
+	}
+	pol.NoLeet = true
+	if err := pol.CheckString("dra9on#1", "", ""); err != nil {
+		t.Errorf("no error expected with NoLeet, got %v", err)
+	}
+	// NUL characters are ignored, since they would end unified strings.
+	for _, m := range []map[rune]rune{{0: 'a'}, {'q': 0}} {
+		pol := *DefaultPolicy
+		pol.LeetMap = m
+		if err := pol.Validate(); err == nil {
+			t.Errorf("%q: expected invalid policy", m)
+		}
+		if params := pol.params(); params.unifyMap != DefaultPolicy.params().unifyMap {
+			t.Errorf("%q: expected unify map unchanged", m)
+		}
+		if err := pol.CheckString("Dragon!2x", "", ""); err != ErrWord {
+			t.Errorf("%q: expected ErrWord, got %v", m, err)
+		}
+	}
+	// Chained substitutions are resolved against the built-in ones.
+	pol.NoLeet = false
+	pol.LeetMap = map[rune]rune{'4': 'a', '@': '4', '9': 'g', 'q': '9', '(': 'c', 'k': '('}
+	want := pol.params().unifyMap
+	for i := 0; i < 20; i++ {
+		if pol.params().unifyMap != want {
+			t.Fatal("unify map depends on the order of LeetMap entries")
+		}
+	}
+	if want['q'] != '9' || want['k'] != '(' {
+		t.Errorf("expected chained entries to map to the substituted characters, got %q, %q", want['q'], want['k'])
+	}
 }
 
-func TestParsePolicy(t *testing.T) {
-	vectors := []struct {
-		s string
-		p *Policy
+func TestMinUnique(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.Min = [5]int{8, 8, 8, 8, 8}
+	pol.MatchLength = 0
+	pol.MinUnique = 3
+	for _, v := range []string{"ababababab", "ффффыыыыы"} {
+		if err := pol.Check([]byte(v), nil, nil); err != ErrFewUnique {
+			t.Errorf("%q: expected ErrFewUnique, got %v", v, err)
+		}
+	}
+	if err := pol.Check([]byte("ффффыыыыыд"), nil, nil); err == ErrFewUnique {
+		t.Errorf("unexpected ErrFewUnique")
+	}
+	if errs := pol.CheckAll([]byte("aaaa"), nil, nil); !containsError(errs, ErrFewUnique) || !containsError(errs, ErrShort) {
+		t.Errorf("expected ErrFewUnique and ErrShort, got %v", errs)
+	}
+	pol.MinUnique = 0
+	if err := pol.Check([]byte("ababababab"), nil, nil); err == ErrFewUnique {
+		t.Errorf("unexpected ErrFewUnique with MinUnique disabled")
+	}
+}
+
+func TestEntropyOnly(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.EntropyOnly = true
+	pol.MinEntropyBits = 40
+	// 7 characters from four classes: 7*log2(95) is about 46 bits.
+	for _, v := range []string{"p@ssw0rD", "Ab1!xyz", "1234567890123"} {
+		if err := pol.Check([]byte(v), nil, nil); err != nil {
+			t.Errorf("%q: no error expected, got %v", v, err)
+		}
+	}
+	for _, v := range []string{"abcdef", "12345678"} {
+		if err := pol.Check([]byte(v), nil, nil); err != ErrSimple {
+			t.Errorf("%q: expected ErrSimple, got %v", v, err)
+		}
+	}
+	if err := pol.Check([]byte("Ab1!xyz"), []byte("Ab1!xyz"), nil); err != ErrSame {
+		t.Errorf("expected ErrSame, got %v", err)
+	}
+	errs := pol.CheckAll([]byte("abc"), []byte("ABC"), nil)
+	if expected := []error{ErrSame, ErrSimple}; !reflect.DeepEqual(errs, expected) {
+		t.Errorf("expected %v, got %v", expected, errs)
+	}
+	if errs := pol.CheckAll([]byte("Ab1!xyz"), nil, nil); errs != nil {
+		t.Errorf("no errors expected, got %v", errs)
+	}
+	pol.MinEntropyBits = 0
+	if pol.Validate() == nil {
+		t.Errorf("expected validation error for entropy mode without bits")
+	}
+}
+
+func TestSimilarPrecedence(t *testing.T) {
+	old := []byte("mooseaxon")
+	for _, v := range []struct {
+		new string
+		err error
 	}{
-		{
-			"min=disabled,16,17,18,19 max=20 passphrase=21 match=22 similar=deny",
-			&Policy{
-				Min:             [5]int{Disabled, 16, 17, 18, 19},
-				Max:             20,
-				PassphraseWords: 21,
-				MatchLength:     22,
-				DenySimilar:     true,
-			},
-		},
-		{
-			"min=10,disabled,111,1222,13 max=12345 passphrase=9876 match=1 similar=permit",
-			&Policy{
-				Min:             [5]int{10, Disabled, 111, 1222, 13},
-				Max:             12345,
-				PassphraseWords: 9876,
-				MatchLength:     1,
-				DenySimilar:     false,
-			},
-		},
-		{
-			"min=10,disabled,111,1222,13\nmax=12345\npassphrase=9876\nmatch=1\nsimilar=permit",
-			&Policy{
-				Min:             [5]int{10, Disabled, 111, 1222, 13},
-				Max:             12345,
-				PassphraseWords: 9876,
-				MatchLength:     1,
-				DenySimilar:     false,
-			},
-		},
+		{"zebra1", ErrShort},              // too short, not similar
+		{"mooseax", ErrSimilar},           // too short and similar
+		{"mooseaxon1", ErrSimilar},        // too simple and similar
+		{"tigerlily1999", ErrSimpleShort}, // too simple, not similar
+		{"mooseaxon", ErrSame},
+	} {
+		if err := DefaultPolicy.Check([]byte(v.new), old, nil); err != v.err {
+			t.Errorf("%q: expected %v, got %v", v.new, v.err, err)
+		}
+	}
+	pol := *DefaultPolicy
+	pol.DenySimilar = false
+	if err := pol.Check([]byte("moosea"), old, nil); err != ErrShort {
+		t.Errorf("expected ErrShort without DenySimilar, got %v", err)
+	}
+}
+
+func TestDenyShifted(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.DenySimilar = false
+	old := []byte("dw1lIojbTBrq/gii7")
+	shifted := []string{
+		"dw1lIojbTBrq/gii8",
+		"dw1lIojbTBrq/gii2024",
+		"DW1LIOJBTBRQ/GII9",
+		"ex2mJpkcUCsr/hjj8", // letters and digits shifted by 1
+		"fy1nKqldVDts/ikk7", // letters shifted by 2
+	}
+	for _, v := range shifted {
+		pol.DenyShifted = false
+		if err := pol.Check([]byte(v), old, nil); err != nil {
+			t.Errorf("%q: no error expected, got %v", v, err)
+		}
+		pol.DenyShifted = true
+		if err := pol.Check([]byte(v), old, nil); err != ErrSimilar {
+			t.Errorf("%q: expected ErrSimilar, got %v", v, err)
+		}
+	}
+	for _, v := range []string{"dw1lIojbTBrq/gjj7", "fy1nKqldVDts/ikk", "Xb4#fQm9pT!sLz2"} {
+		if err := pol.Check([]byte(v), old, nil); err != nil {
+			t.Errorf("%q: no error expected, got %v", v, err)
+		}
+	}
+	// Changing only the letter case isn't a shift.
+	old = []byte("xq#klmbvtrw!")
+	pass := []byte("XQ#KLMBVTRW!")
+	if pol.shifted(pass, old) {
+		t.Errorf("%q: unexpected shift", pass)
+	}
+	if errs := pol.CheckAll(pass, old, nil); !containsError(errs, ErrSame) || containsError(errs, ErrSimilar) {
+		t.Errorf("%q: CheckAll: expected ErrSame without ErrSimilar, got %v", pass, errs)
+	}
+	pol.CaseSensitive = true
+	if pol.shifted(pass, old) {
+		t.Errorf("%q: unexpected shift with CaseSensitive", pass)
+	}
+}
+
+func TestMaxSequence(t *testing.T) {
+	for _, v := range []struct {
+		password string
+		longest  int
+	}{
+		{"", 0},
+		{"x", 1},
+		{"abcdef", 6},
+		{"54321", 5},
+		{"xyzab", 5},
+		{"8901", 4},
+		{"AbCd", 4},
+		{"abcba", 3},
+		{"a1b2c3", 1},
+		{"p@ss-12345-w0rd", 5},
+		{"aaa", 1},
+	} {
+		if n := longestSequence([]byte(v.password)); n != v.longest {
+			t.Errorf("%q: expected %d, got %d", v.password, v.longest, n)
+		}
+	}
+	pol := *DefaultPolicy
+	pol.MaxSequence = 3
+	if err := pol.Check([]byte("dw1lIojbTBrq/gii6789"), nil, nil); err != ErrSeq {
+		t.Errorf("expected ErrSeq, got %v", err)
+	}
+	if err := pol.Check([]byte("dw1lIojbTBrq/gii789"), nil, nil); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+}
+
+func TestDenyPatterns(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.DenyPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`2026`),
+		regexp.MustCompile(`(?i)bluefalcon`),
+	}
+	for _, v := range []string{"dw1lIojbTBrq/gii2026", "dw1lIojbTBrq/BlueFalcon"} {
+		if err := pol.Check([]byte(v), nil, nil); err != ErrDenied {
+			t.Errorf("%q: expected ErrDenied, got %v", v, err)
+		}
+		if errs := pol.CheckAll([]byte(v), nil, nil); !reflect.DeepEqual(errs, []error{ErrDenied}) {
+			t.Errorf("%q: expected ErrDenied from CheckAll, got %v", v, errs)
+		}
+	}
+	if err := pol.Check([]byte("dw1lIojbTBrq/gii2025"), nil, nil); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+	if err := pol.Check([]byte("short"), nil, nil); err != ErrShort {
+		t.Errorf("expected ErrShort, got %v", err)
+	}
+}
+
+func TestSkipDictionary(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.SkipDictionary = true
+	for _, v := range []string{"p@ssw0rd", "dr@gon99", "s3cr3t!!"} {
+		if err := pol.Check([]byte(v), nil, nil); err != nil {
+			t.Errorf("%q: no error expected, got %v", v, err)
+		}
+	}
+	if err := pol.Check([]byte("pass1"), nil, nil); err != ErrShort {
+		t.Errorf("expected ErrShort, got %v", err)
+	}
+	errs := pol.CheckAll([]byte("abcdef12345"), nil, nil)
+	if expected := []error{ErrSimpleShort}; !reflect.DeepEqual(errs, expected) {
+		t.Errorf("expected %v, got %v", expected, errs)
+	}
+}
+
+func TestHighEntropyBypass(t *testing.T) {
+	pol := MustParsePolicy("highentropy=bypass")
+	for _, v := range []struct {
+		password  string
+		def, pass error
+	}{
+		// Random base64 strings of 18 bytes.
+		{"yqJomOyPFfHtcsJZIernEpVE", ErrWord, nil},
+		{"STKCBvMWLCREIcHRYRJnlus0", ErrWord, nil},
+		// Too little randomness for the bypass.
+		{"m__UNimw", ErrWord, ErrWord},
+		{"7cbf05ae21f9", ErrSeq, ErrSeq},
+		{"dw1lIojbTBrq/gii", nil, nil},
+	} {
+		if err := DefaultPolicy.CheckString(v.password, "", ""); err != v.def {
+			t.Errorf("%q: default: expected %v, got %v", v.password, v.def, err)
+		}
+		if err := pol.CheckString(v.password, "", ""); err != v.pass {
+			t.Errorf("%q: bypass: expected %v, got %v", v.password, v.pass, err)
+		}
+		if errs := pol.CheckAll([]byte(v.password), nil, nil); (len(errs) == 0) != (v.pass == nil) {
+			t.Errorf("%q: bypass: unexpected CheckAll result %v", v.password, errs)
+		}
+	}
+}
+
+func TestCaseSensitive(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.NoLeet = true
+	old := []byte("Xq7#mountain")
+	pw := []byte("Zy2!MOUNTAIN")
+	if err := pol.Check(pw, old, nil); err != ErrSimilar {
+		t.Errorf("expected ErrSimilar, got %v", err)
+	}
+	pol.CaseSensitive = true
+	if err := pol.Check(pw, old, nil); err == ErrSimilar {
+		t.Errorf("unexpected ErrSimilar with case-sensitive matching")
+	}
+}
+
+func TestBlank(t *testing.T) {
+	for _, s := range []string{"", "   ", "\t\n", "\u00a0\u3000"} {
+		if err := DefaultPolicy.Check([]byte(s), nil, nil); err != ErrEmpty {
+			t.Errorf("%q: expected ErrEmpty, got %v", s, err)
+		}
+		if errs := DefaultPolicy.CheckAll([]byte(s), nil, nil); len(errs) != 1 || errs[0] != ErrEmpty {
+			t.Errorf("%q: expected ErrEmpty from CheckAll, got %v", s, errs)
+		}
+	}
+	if err := DefaultPolicy.Check(nil, nil, nil); err != ErrEmpty {
+		t.Errorf("nil: expected ErrEmpty, got %v", err)
+	}
+	if err := DefaultPolicy.Check([]byte(" x "), nil, nil); err == ErrEmpty {
+		t.Errorf("unexpected ErrEmpty for non-blank password")
+	}
+}
+
+func TestNul(t *testing.T) {
+	// Without the NUL check, passwdqc would see only "password1".
+	pass := []byte("password1\x00dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU")
+	if err := DefaultPolicy.Check(pass[:9], nil, nil); err == nil {
+		t.Fatal("error expected")
+	}
+	if err := DefaultPolicy.Check(pass, nil, nil); err != ErrNul {
+		t.Errorf("expected ErrNul, got %v", err)
+	}
+	good := pass[10:]
+	if err := DefaultPolicy.Check(good, []byte("old\x00"), nil); err != ErrNul {
+		t.Errorf("expected ErrNul for old password, got %v", err)
+	}
+	if err := DefaultPolicy.Check(good, nil, []byte("\x00user")); err != ErrNul {
+		t.Errorf("expected ErrNul for user name, got %v", err)
 	}
+	if err := DefaultPolicy.Check(good, nil, nil); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+}
+
+func TestCheckAll(t *testing.T) {
+	errs := DefaultPolicy.CheckAll([]byte("pass1"), []byte("pass1"), []byte("pass"))
+	expected := []error{ErrSame, ErrShort, ErrSimpleShort, ErrPersonal, ErrWord}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("expected %v, got %v", expected, errs)
+	}
+	errs = DefaultPolicy.CheckAll([]byte("abcdef12345"), nil, nil)
+	expected = []error{ErrSimpleShort, ErrSeq}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("expected %v, got %v", expected, errs)
+	}
+	if errs := DefaultPolicy.CheckAll([]byte("dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU"), nil, nil); errs != nil {
+		t.Errorf("no errors expected, got %v", errs)
+	}
+	if errs := DefaultPolicy.CheckAll(nil, nil, nil); !reflect.DeepEqual(errs, []error{ErrEmpty}) {
+		t.Errorf("expected ErrEmpty, got %v", errs)
+	}
+	// The first error must match Check.
+	for _, pw := range []string{"password1", "JJJRedRyIdHCJQ131", "abc", "qwertyuiop1234"} {
+		err := DefaultPolicy.Check([]byte(pw), []byte("131QJCHdIyRdeRJJJ"), nil)
+		errs := DefaultPolicy.CheckAll([]byte(pw), []byte("131QJCHdIyRdeRJJJ"), nil)
+		if len(errs) == 0 || err == nil {
+			t.Fatalf("%q: expected errors", pw)
+		}
+		if !containsError(errs, err) {
+			t.Errorf("%q: expected %v in %v", pw, err, errs)
+		}
+	}
+}
 
+func TestCheckWithEmail(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.Min = [5]int{8, 8, 8, 8, 8}
+	pass := []byte("johnsmith")
+	if err := pol.Check(pass, nil, nil); err != nil {
+		t.Fatalf("no error expected without email, got %v", err)
+	}
+	vectors := []struct {
+		pass, email string
+		err         error
+	}{
+		{"johnsmith", "johnsmith@corp.com", ErrPersonal},
+		{"megacorp7", "johnsmith@megacorp.com", ErrPersonal},
+		{"megacorp7", "megacorp.com", ErrPersonal},
+		{"Snowfall17", "johnsmith@corp.com", nil},
+		{"johnsmith", "johnsmith@corp.com\x00", ErrNul},
+	}
 	for i, v := range vectors {
-		p, err := ParsePolicy(v.s)
+		if err := pol.CheckWithEmail([]byte(v.pass), nil, v.email); err != v.err {
+			t.Errorf("%d: expected %v, got %v", i, v.err, err)
+		}
+	}
+}
+
+func TestForbidUsername(t *testing.T) {
+	pass := []byte("dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU")
+	user := []byte("OJBTBRQ")
+	pol := *DefaultPolicy
+	if err := pol.Check(pass, nil, user); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+	pol.ForbidUsername = true
+	if err := pol.Check(pass, nil, user); err != ErrPersonal {
+		t.Errorf("expected ErrPersonal, got %v", err)
+	}
+	if err := pol.Check(pass, nil, []byte("brewery")); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+	if err := pol.Check(pass, nil, []byte{}); err != nil {
+		t.Errorf("no error expected for empty user name, got %v", err)
+	}
+}
+
+func TestRequireCharacters(t *testing.T) {
+	passwords := []string{
+		"dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU",
+		"correct horse battery staple",
+		"CORRECTHORSEBATTERYSTAPLE",
+		"4815162342",
+		"\u0444\u043e\u0442\u043e\u0433\u0440\u0430\u0444\u0438\u044f",
+	}
+	requirements := []struct {
+		err   error
+		class func(rune) bool
+	}{
+		{ErrNoDigit, func(c rune) bool { return c >= '0' && c <= '9' }},
+		{ErrNoUpper, func(c rune) bool { return c >= 'A' && c <= 'Z' }},
+		{ErrNoLower, func(c rune) bool { return c >= 'a' && c <= 'z' }},
+		{ErrNoSymbol, func(c rune) bool { return c < 0x80 && !unicode.IsLetter(c) && !unicode.IsDigit(c) }},
+	}
+	for mask := 0; mask < 16; mask++ {
+		pol := *DefaultPolicy
+		pol.RequireDigit = mask&1 != 0
+		pol.RequireUpper = mask&2 != 0
+		pol.RequireLower = mask&4 != 0
+		pol.RequireSymbol = mask&8 != 0
+		for _, pw := range passwords {
+			expected := DefaultPolicy.Check([]byte(pw), nil, nil)
+			for i, r := range requirements {
+				if mask&(1<<uint(i)) != 0 && strings.IndexFunc(pw, r.class) < 0 {
+					expected = r.err
+					break
+				}
+			}
+			if err := pol.Check([]byte(pw), nil, nil); err != expected {
+				t.Errorf("%04b %q: expected %v, got %v", mask, pw, expected, err)
+			}
+		}
+	}
+	pol := *DefaultPolicy
+	pol.RequireDigit, pol.RequireSymbol = true, true
+	errs := pol.CheckAll([]byte("CORRECTHORSEBATTERYSTAPLE"), nil, nil)
+	if !containsError(errs, ErrNoDigit) || !containsError(errs, ErrNoSymbol) || containsError(errs, ErrNoUpper) {
+		t.Errorf("expected ErrNoDigit and ErrNoSymbol, got %v", errs)
+	}
+}
+
+func TestOnReject(t *testing.T) {
+	var mu sync.Mutex
+	rejects := make(map[Reason]int)
+	pol := *DefaultPolicy
+	pol.OnReject = func(r Reason) {
+		mu.Lock()
+		rejects[r]++
+		mu.Unlock()
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pol.Check([]byte("pass"), nil, nil)
+			pol.Check([]byte("dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU"), nil, nil)
+			pol.Check(nil, nil, nil)
+		}()
+	}
+	wg.Wait()
+	expected := map[Reason]int{ReasonShort: 10, ReasonEmpty: 10}
+	if !reflect.DeepEqual(rejects, expected) {
+		t.Errorf("expected %v, got %v", expected, rejects)
+	}
+}
+
+func TestMaxPasswordLength(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.Max = MaxPasswordLength * 2
+	pass := []byte(strings.Repeat("dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU", MaxPasswordLength/43+1))
+	if err := pol.Check(pass, nil, nil); err != ErrLong {
+		t.Errorf("expected ErrLong, got %v", err)
+	}
+	if err := pol.Check(pass[:100], nil, nil); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+	// Long old passwords and user names are truncated.
+	old := bytes.Repeat([]byte("Kx#mLqWpZ"), 200000/9)
+	pass = pass[:1000]
+	if err := pol.Check(pass, old, old); err != nil {
+		t.Errorf("no error expected with long old password, got %v", err)
+	}
+	if errs := pol.CheckAll(pass, old, old); errs != nil {
+		t.Errorf("CheckAll: no errors expected with long old password, got %v", errs)
+	}
+	if pol.Similar(pass, old) || pol.PairSimilar(pass, old) {
+		t.Error("expected long old password not to be similar")
+	}
+	if n := pol.EffectiveLength(pass, old, old); n != len(pass) {
+		t.Errorf("expected effective length %d, got %d", len(pass), n)
+	}
+}
+
+func TestLongFastPath(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.Max = 20
+	pass := []byte("dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU")
+	if err := pol.Check(pass, nil, nil); err != ErrLong {
+		t.Errorf("expected ErrLong, got %v", err)
+	}
+	if err := pol.Check(pass, pass, nil); err != ErrSame {
+		t.Errorf("expected ErrSame, got %v", err)
+	}
+	pol.Max = 8
+	if err := pol.Check(pass, nil, nil); err == ErrLong {
+		t.Errorf("unexpected ErrLong for truncated password")
+	}
+	if err := pol.Check(pass, pass[:8], nil); err != ErrSame {
+		t.Errorf("expected ErrSame for truncated password, got %v", err)
+	}
+}
+
+func TestTruncationWarning(t *testing.T) {
+	pol := *DefaultPolicy
+	pass := []byte("dw1lIojbTBrq/gii")
+	if err := pol.TruncationWarning(pass); err != nil {
+		t.Errorf("no warning expected, got %v", err)
+	}
+	pol.Max = 8
+	if err := pol.TruncationWarning(pass); err != ErrTruncated {
+		t.Errorf("expected ErrTruncated, got %v", err)
+	}
+	if err := pol.TruncationWarning(pass[:8]); err != nil {
+		t.Errorf("no warning expected for 8 characters, got %v", err)
+	}
+	if r := ErrTruncated.Reason(); r != ReasonTruncated || r.String() != "truncated" {
+		t.Errorf("unexpected reason %s", r)
+	}
+}
+
+func TestUnlimitedMax(t *testing.T) {
+	pass := []byte("dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU")
+	for _, config := range []string{"max=0", "max=unlimited", "max=-1"} {
+		pol, err := ParsePolicy(config)
 		if err != nil {
 			t.Fatal(err)
 		}
-		if *p != *v.p {
-			t.Errorf("%d: incorrect parsing: expected %v, got %v", i, v.p, p)
+		if err := pol.Check(pass, nil, nil); err != nil {
+			t.Errorf("%s: no error expected, got %v", config, err)
+		}
+		if _, err := NewPolicy(config); err != nil {
+			t.Errorf("%s: NewPolicy: unexpected error %v", config, err)
 		}
 	}
+	pol := MustParsePolicy("max=0")
+	if s := pol.String(); !strings.Contains(s, " max=unlimited ") {
+		t.Errorf("expected max=unlimited in %q", s)
+	}
 }
 
-func TestParsePolicyErrors(t *testing.T) {
-	vectors := []string{
-		"",
-		" ",
-		"\n",
-		"max=similar=deny",
-		"min=",
-		"min=disabled,16,17,18",
-		"min=dosabled,16,17,18,19 max=20 passphrase=21 match=22 similar=deny",
-		"min=10,disabled,111,1222,13 max=0x12345 passphrase=9876 match=1 similar=permit",
-		"min=10,disabled,111,1222,13 max=12345 passphrase=what match=1 similar=permit",
-		"min=10,disabled,111,1222,13 max=12345 passphrase=1 match= similar=permit",
-		"min=10,disabled,111,1222,13 max=12345 passphrase=1 match= similar=no",
-		"min=10,disabled,111,1222,13 max=12345 passphrase=1 match= similar=no",
-		"max=123 blah=1",
+func TestIsSatisfiable(t *testing.T) {
+	vectors := []struct {
+		config string
+		ok     bool
+	}{
+		{DefaultPolicy.String(), true},
+		{"min=disabled,disabled,disabled,disabled,disabled", false},
+		{"min=disabled,disabled,11,disabled,disabled passphrase=3", false},
+		{"min=disabled,disabled,11,disabled,7 passphrase=0", true},
+		{"min=disabled,24,11,8,7 max=6", false},
+		{"min=disabled,24,11,8,7 max=10", true},
+		{"min=disabled,24,11,disabled,disabled", false},
+		{"min=disabled,24,11,9,9 max=8", false},
+		{"min=disabled,disabled,11,disabled,10 max=12 passphrase=7", true},
+		{"min=disabled,24,11,8,7 max=unlimited", true},
 	}
 	for i, v := range vectors {
-		_, err := ParsePolicy(v)
-		if err == nil {
-			t.Errorf("%d: expected error for %q", i, v)
+		p := MustParsePolicy(v.config)
+		if ok := p.IsSatisfiable(); ok != v.ok {
+			t.Errorf("%d: %q: expected %v, got %v", i, v.config, v.ok, ok)
+		}
+	}
+}
+
+func TestSimilar(t *testing.T) {
+	old := []byte("131QJCHdIyRdeRJJJ")
+	if !DefaultPolicy.Similar([]byte("JJJRedRyIdHCJQ131"), old) {
+		t.Error("expected similar passwords")
+	}
+	if DefaultPolicy.Similar([]byte("dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU"), old) {
+		t.Error("expected different passwords")
+	}
+	pol := *DefaultPolicy
+	pol.DenySimilar = false
+	if pol.Similar([]byte("JJJRedRyIdHCJQ131"), old) {
+		t.Error("expected no similarity with DenySimilar disabled")
+	}
+	pol.DenySimilar = true
+	pol.MatchLength = 0
+	if pol.Similar([]byte("JJJRedRyIdHCJQ131"), old) {
+		t.Error("expected no similarity with MatchLength disabled")
+	}
+}
+
+func TestDiscountTrailingDigits(t *testing.T) {
+	pol := *DefaultPolicy
+	pass := []byte("Kx#mLq2024")
+	if err := pol.Check(pass, nil, nil); err != nil {
+		t.Fatalf("no error expected without DiscountTrailingDigits, got %v", err)
+	}
+	pol.DiscountTrailingDigits = true
+	if err := pol.Check(pass, nil, nil); err != ErrShort {
+		t.Errorf("expected ErrShort, got %v", err)
+	}
+	if errs := pol.CheckAll(pass, nil, nil); len(errs) == 0 || errs[0] != ErrShort {
+		t.Errorf("CheckAll: expected ErrShort first, got %v", errs)
+	}
+	// Passwords strong enough without the digits are accepted.
+	if err := pol.Check([]byte("Mooseaxon#7Tq2024"), nil, nil); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+	if got := pol.discountDigits([]byte("20242024")); string(got) != "20242024" {
+		t.Errorf("expected digits-only password unchanged, got %q", got)
+	}
+	// The old password is compared with the whole new one.
+	old := []byte("Kx#mLqWpZ")
+	pass = []byte("Kx#mLqWpZ2024")
+	if err := pol.Check(pass, old, nil); err != ErrSimilar {
+		t.Errorf("expected ErrSimilar, got %v", err)
+	}
+	if errs := pol.CheckAll(pass, old, nil); !reflect.DeepEqual(errs, []error{ErrSimilar}) {
+		t.Errorf("CheckAll: expected ErrSimilar, got %v", errs)
+	}
+	if err := pol.Check(old, old, nil); err != ErrSame {
+		t.Errorf("expected ErrSame, got %v", err)
+	}
+	if err := pol.Check([]byte("Mooseaxon#7Tq2024"), old, nil); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+}
+
+func TestSimilarMatchLength(t *testing.T) {
+	old := []byte("131QJCHdIyRdeRJJJ")
+	pass := []byte("JJJRedRyIdHCJQ131")
+	pol := *DefaultPolicy
+	pol.SimilarMatchLength = 20
+	if pol.Similar(pass, old) {
+		t.Error("expected no similarity with SimilarMatchLength longer than the passwords")
+	}
+	if err := pol.Check(pass, old, nil); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+	if errs := pol.CheckAll(pass, old, nil); errs != nil {
+		t.Errorf("CheckAll: no errors expected, got %v", errs)
+	}
+	// MatchLength still applies to dictionary words.
+	if err := pol.CheckString("Dragon!2x", "", ""); err == nil {
+		t.Error("expected dictionary word to be rejected")
+	}
+	pol.SimilarMatchLength = 0
+	pol.MatchLength = 20
+	if err := pol.Check(pass, old, nil); err != nil {
+		t.Errorf("no error expected with long MatchLength, got %v", err)
+	}
+	pol.SimilarMatchLength = 4
+	if !pol.Similar(pass, old) {
+		t.Error("expected similar passwords with SimilarMatchLength")
+	}
+	if err := pol.Check(pass, old, nil); err != ErrSimilar {
+		t.Errorf("expected ErrSimilar, got %v", err)
+	}
+	if s := pol.DebugParams(); !strings.Contains(s, " match_length=20 similar_match_length=4 ") {
+		t.Errorf("unexpected params %q", s)
+	}
+	p, err := ParsePolicy("similarmatch=6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.SimilarMatchLength != 6 {
+		t.Errorf("expected SimilarMatchLength 6, got %d", p.SimilarMatchLength)
+	}
+	if _, err := ParsePolicy("similarmatch=x"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestPairSimilar(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.DenySimilar = false
+	a := []byte("131QJCHdIyRdeRJJJ")
+	b := []byte("131QJCHdIyRdeRJJJ2019")
+	if !pol.PairSimilar(a, b) || !pol.PairSimilar(b, a) {
+		t.Error("expected similar passwords in both orders")
+	}
+	if pol.PairSimilar(a, []byte("dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU")) {
+		t.Error("expected different passwords")
+	}
+	if pol.PairSimilar(a, nil) || pol.PairSimilar(nil, a) {
+		t.Error("expected empty password not to be similar")
+	}
+	pol.MatchLength = 0
+	if pol.PairSimilar(a, b) {
+		t.Error("expected no similarity with MatchLength disabled")
+	}
+}
+
+func checkPasswordsFromFile(t *testing.T, filename string) {
+	fmt.Printf("[INFO] Checking common passwords from %s\n", filename)
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	z, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+	st, err := DefaultPolicy.AuditCorpus(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pw := range st.AcceptedPasswords {
+		fmt.Printf("[INFO] Accepted password: %q\n", pw)
+	}
+	for k, v := range st.Reasons {
+		fmt.Printf("[INFO] %d passwords: %s\n", v, k)
+	}
+	fmt.Printf("[INFO] Rejected %d of %d passwords\n", st.Rejected, st.Total)
+	if st.Accepted+st.Rejected != st.Total {
+		t.Errorf("accepted %d + rejected %d != total %d", st.Accepted, st.Rejected, st.Total)
+	}
+}
+
+func TestAuditCorpus(t *testing.T) {
+	st, err := DefaultPolicy.AuditCorpus(strings.NewReader("short\r\n\ndw1lIojbTBrq/gii\npassword\nNcc1701!enterprise\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Total != 4 || st.Accepted != 2 || st.Rejected != 2 {
+		t.Errorf("unexpected totals: %+v", st)
+	}
+	if st.Reasons[ReasonShort] != 1 || st.Reasons[ReasonSimpleShort] != 1 {
+		t.Errorf("unexpected reasons: %v", st.Reasons)
+	}
+	if len(st.AcceptedPasswords) != 2 || st.AcceptedPasswords[0] != "dw1lIojbTBrq/gii" {
+		t.Errorf("unexpected accepted passwords: %q", st.AcceptedPasswords)
+	}
+}
+
+func TestCommonPasswords(t *testing.T) {
+	checkPasswordsFromFile(t, "testdata/passwords.txt.gz")
+}
+
+func TestParsePolicy(t *testing.T) {
+	vectors := []struct {
+		s string
+		p *Policy
+	}{
+		{
+			"min=disabled,16,17,18,19 max=20 passphrase=21 match=22 similar=deny",
+			&Policy{
+				Min:             [5]int{Disabled, 16, 17, 18, 19},
+				Max:             20,
+				PassphraseWords: 21,
+				MatchLength:     22,
+				DenySimilar:     true,
+				RandomBits:      47,
+			},
+		},
+		{
+			"min=10,disabled,111,1222,13 max=12345 passphrase=9876 match=1 similar=permit",
+			&Policy{
+				Min:             [5]int{10, Disabled, 111, 1222, 13},
+				Max:             12345,
+				PassphraseWords: 9876,
+				MatchLength:     1,
+				DenySimilar:     false,
+				RandomBits:      47,
+			},
+		},
+		{
+			"min=10,disabled,111,1222,13\nmax=12345\npassphrase=9876\nmatch=1\nsimilar=permit",
+			&Policy{
+				Min:             [5]int{10, Disabled, 111, 1222, 13},
+				Max:             12345,
+				PassphraseWords: 9876,
+				MatchLength:     1,
+				DenySimilar:     false,
+				RandomBits:      47,
+			},
+		},
+		{
+			"  min=10,disabled,111,1222,13\t max=12345\r\n\tpassphrase=9876  match=1\t\tsimilar=permit \n",
+			&Policy{
+				Min:             [5]int{10, Disabled, 111, 1222, 13},
+				Max:             12345,
+				PassphraseWords: 9876,
+				MatchLength:     1,
+				DenySimilar:     false,
+				RandomBits:      47,
+			},
+		},
+		{
+			"require=digit,upper,symbol",
+			&Policy{
+				Min:             DefaultPolicy.Min,
+				Max:             DefaultPolicy.Max,
+				PassphraseWords: DefaultPolicy.PassphraseWords,
+				MatchLength:     DefaultPolicy.MatchLength,
+				DenySimilar:     true,
+				RandomBits:      47,
+				RequireDigit:    true,
+				RequireUpper:    true,
+				RequireSymbol:   true,
+			},
+		},
+		{
+			"require=lower require=none",
+			DefaultPolicy,
+		},
+	}
+
+	for i, v := range vectors {
+		p, err := ParsePolicy(v.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(p, v.p) {
+			t.Errorf("%d: incorrect parsing: expected %v, got %v", i, v.p, p)
+		}
+		q, err := ParsePolicy(p.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(p, q) {
+			t.Errorf("%d: incorrect round trip: expected %v, got %v", i, p, q)
+		}
+	}
+}
+
+func TestMustParsePolicy(t *testing.T) {
+	p := MustParsePolicy("max=20")
+	if p.Max != 20 {
+		t.Errorf("expected max 20, got %d", p.Max)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	MustParsePolicy("max=twenty")
+}
+
+func TestPassphraseMinWordLen(t *testing.T) {
+	pass := []byte("a an the cat")
+	pol := *DefaultPolicy
+	pol.PassphraseWords = 4
+	pol.Min[2] = 8
+	if r := pol.CheckResult(pass, nil, nil); !r.OK || !r.IsPassphrase {
+		t.Fatalf("expected passphrase to be accepted, got %+v", r)
+	}
+	pol.PassphraseMinWordLen = 3
+	if r := pol.CheckResult(pass, nil, nil); r.OK || r.IsPassphrase {
+		t.Errorf("expected passphrase to be rejected, got %+v", r)
+	}
+	if err := pol.Check([]byte("one two three cats"), nil, nil); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+}
+
+func TestDenyReversed(t *testing.T) {
+	pol := *DefaultPolicy
+	old := []byte("Password2")
+	pass := []byte("Xq9#2DROWSSAP-zT7!")
+	if err := pol.Check(pass, old, nil); err != nil {
+		t.Fatalf("no error expected, got %v", err)
+	}
+	pol.DenyReversed = true
+	if err := pol.Check(pass, old, nil); err != ErrSimilar {
+		t.Errorf("expected ErrSimilar, got %v", err)
+	}
+	if err := pol.Check([]byte("Xq9#Password2-zT7!"), old, nil); err != nil {
+		t.Errorf("no error expected for non-reversed password, got %v", err)
+	}
+	// A palindrome equals its reverse: the same password is still
+	// rejected as the same, and containing it is rejected as similar.
+	old = []byte("Level")
+	if err := pol.Check(old, old, nil); err != ErrSame {
+		t.Errorf("expected ErrSame, got %v", err)
+	}
+	if err := pol.Check([]byte("Xq9#level-zT7!"), old, nil); err != ErrSimilar {
+		t.Errorf("expected ErrSimilar for palindrome, got %v", err)
+	}
+}
+
+func TestPolicyFromEnv(t *testing.T) {
+	p, err := PolicyFromEnv("TEST_PASSWORDCHECK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, DefaultPolicy) || p == DefaultPolicy {
+		t.Errorf("expected a copy of DefaultPolicy, got %v", p)
+	}
+	t.Setenv("TEST_PASSWORDCHECK_MIN", "disabled,16,17,18,19")
+	t.Setenv("TEST_PASSWORDCHECK_MAX", "20")
+	t.Setenv("TEST_PASSWORDCHECK_SIMILAR", "permit")
+	t.Setenv("TEST_PASSWORDCHECK_MATCH", "")
+	p, err = PolicyFromEnv("TEST_PASSWORDCHECK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := *DefaultPolicy
+	expected.Min = [5]int{Disabled, 16, 17, 18, 19}
+	expected.Max = 20
+	expected.DenySimilar = false
+	if !reflect.DeepEqual(p, &expected) {
+		t.Errorf("expected %v, got %v", &expected, p)
+	}
+	t.Setenv("TEST_PASSWORDCHECK_PASSPHRASE", "three")
+	if _, err := PolicyFromEnv("TEST_PASSWORDCHECK"); err == nil || !strings.Contains(err.Error(), "TEST_PASSWORDCHECK_PASSPHRASE") {
+		t.Errorf("expected error naming the variable, got %v", err)
+	}
+}
+
+func TestDebugParams(t *testing.T) {
+	p := MustParsePolicy("min=disabled,16,17,18,19 max=20 passphrase=21 wordlen=3 match=22 similar=deny case=match leet=ignore")
+	expected := "min=[2147483647 16 17 18 19] max=20 passphrase_words=21 passphrase_min_word_len=3 match_length=22 similar_match_length=22 similar_deny=1 non_ascii_letters=0 skip_same=0 unify_map=0"
+	if s := p.DebugParams(); s != expected {
+		t.Errorf("expected %q, got %q", expected, s)
+	}
+}
+
+func TestPolicyString(t *testing.T) {
+	s := "min=disabled,16,17,18,19 max=20 passphrase=21 wordlen=3 unique=5 sequence=4 match=22 similarmatch=23 similar=deny reversed=deny shifted=deny random=85 case=match leet=ignore username=deny dictionary=skip highentropy=bypass keyboard=deny date=deny trailingdigits=discount nonascii=letters dictfile=none mode=entropy entropy=60 require=digit,lower"
+	p, err := ParsePolicy(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != s {
+		t.Errorf("expected %q, got %q", s, p.String())
+	}
+}
+
+func TestPolicyDiff(t *testing.T) {
+	if d := DefaultPolicy.Diff(DefaultPolicy); d == nil || len(d) != 0 {
+		t.Errorf("expected empty slice, got %#v", d)
+	}
+	p := MustParsePolicy("max=512 similar=permit require=digit")
+	expected := []string{"max: 1024 -> 512", "similar: deny -> permit", "require: none -> digit"}
+	if d := DefaultPolicy.Diff(p); !reflect.DeepEqual(d, expected) {
+		t.Errorf("expected %q, got %q", expected, d)
+	}
+}
+
+func TestPolicyGob(t *testing.T) {
+	p := MustParsePolicy("min=disabled,16,17,18,19 max=20 similar=permit leet=ignore username=deny")
+	p.OnReject = func(Reason) {}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(p); err != nil {
+		t.Fatal(err)
+	}
+	var q Policy
+	if err := gob.NewDecoder(&buf).Decode(&q); err != nil {
+		t.Fatal(err)
+	}
+	p.OnReject = nil
+	if !reflect.DeepEqual(p, &q) {
+		t.Errorf("expected %v, got %v", p, &q)
+	}
+	if err := q.GobDecode([]byte("max=what")); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestParsePolicyStrict(t *testing.T) {
+	config := DefaultPolicy.String()
+	if _, err := ParsePolicyStrict(config); err != nil {
+		t.Fatal(err)
+	}
+	for _, it := range DefaultPolicy.configItems() {
+		s := config + " " + it.name + "=" + it.value
+		if _, err := ParsePolicy(s); err != nil {
+			t.Errorf("%s: ParsePolicy: %v", it.name, err)
+		}
+		_, err := ParsePolicyStrict(s)
+		var dup *DuplicateItemError
+		if !errors.As(err, &dup) || dup.Name != it.name {
+			t.Errorf("%s: expected DuplicateItemError, got %v", it.name, err)
+		}
+	}
+	if _, err := ParsePolicyStrict("max=10 max=20"); err == nil || err.Error() != `duplicate item: "max"` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestParsePolicyErrors(t *testing.T) {
+	vectors := []string{
+		"",
+		" ",
+		"\n",
+		"\t \r\n",
+		"max =20",
+		"max= 20",
+		"max=similar=deny",
+		"min=",
+		"min=disabled,16,17,18",
+		"min=dosabled,16,17,18,19 max=20 passphrase=21 match=22 similar=deny",
+		"min=10,disabled,111,1222,13 max=0x12345 passphrase=9876 match=1 similar=permit",
+		"min=10,disabled,111,1222,13 max=12345 passphrase=what match=1 similar=permit",
+		"wordlen=two",
+		"random=23",
+		"random=137",
+		"min=10,disabled,111,1222,13 max=12345 passphrase=1 match= similar=permit",
+		"min=10,disabled,111,1222,13 max=12345 passphrase=1 match= similar=no",
+		"min=10,disabled,111,1222,13 max=12345 passphrase=1 match= similar=no",
+		"max=123 blah=1",
+		"case=yes",
+		"leet=no",
+		"username=forbid",
+		"require=digits",
+		"require=digit,,upper",
+		"require=",
+	}
+	for i, v := range vectors {
+		_, err := ParsePolicy(v)
+		if err == nil {
+			t.Errorf("%d: expected error for %q", i, v)
+		}
+	}
+}
+
+func TestCheckResult(t *testing.T) {
+	r := DefaultPolicy.CheckResult([]byte("pass"), nil, nil)
+	if r.OK || r.Reason != ReasonShort || r.Message != ErrShort.Error() {
+		t.Errorf("expected short password result, got %+v", r)
+	}
+	r = DefaultPolicy.CheckResult([]byte("dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU"), nil, nil)
+	if !r.OK || r.Reason != ReasonNone || r.Message != "" {
+		t.Errorf("expected accepted password result, got %+v", r)
+	}
+	if r.ApproxEntropy < 200 {
+		t.Errorf("expected high entropy, got %d", r.ApproxEntropy)
+	}
+	r = DefaultPolicy.CheckResult([]byte("4815162342Lost!"), nil, nil)
+	if r.IsPassphrase {
+		t.Errorf("password is not a passphrase")
+	}
+	r = DefaultPolicy.CheckResult([]byte("correct horse battery staple"), nil, nil)
+	if !r.OK || !r.IsPassphrase {
+		t.Errorf("expected accepted passphrase, got %+v", r)
+	}
+	r = DefaultPolicy.CheckResult(nil, nil, nil)
+	if r.OK || r.Reason != ReasonEmpty || r.ApproxEntropy != 0 {
+		t.Errorf("expected empty password result, got %+v", r)
+	}
+	r = DefaultPolicy.CheckResult(bytes.Repeat([]byte("Ab1!"), MaxPasswordLength), nil, nil)
+	if r.OK || r.Reason != ReasonLong || r.ApproxEntropy != 0 || r.IsPassphrase {
+		t.Errorf("expected long password result without details, got %+v", r)
+	}
+	r = DefaultPolicy.CheckResult([]byte("pass"), nil, nil)
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Result
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != r {
+		t.Errorf("JSON round trip: expected %+v, got %+v from %s", r, decoded, b)
+	}
+}
+
+func TestAllReasons(t *testing.T) {
+	errs := AllReasons()
+	if len(errs) == 0 || errs[0] != ErrEmpty {
+		t.Fatalf("expected ErrEmpty first, got %v", errs)
+	}
+	seen := make(map[Reason]bool)
+	for i, e := range errs {
+		r := e.Reason()
+		if r == ReasonNone || r == ReasonUnknown || seen[r] {
+			t.Errorf("%d: unexpected reason %s", i, r)
+		}
+		if i > 0 && r < errs[i-1].Reason() {
+			t.Errorf("%d: reasons are not in order", i)
+		}
+		seen[r] = true
+	}
+	errs[0] = nil
+	if AllReasons()[0] != ErrEmpty {
+		t.Errorf("AllReasons returned internal slice")
+	}
+}
+
+func TestCheckPassphrase(t *testing.T) {
+	vectors := []struct {
+		s     string
+		words int
+		err   error
+	}{
+		{"correct horse battery staple", 4, nil},
+		{"horse battery", 2, ErrSimpleShort},
+		{"password", 1, ErrSimpleShort},
+		{"", 0, ErrEmpty},
+		{strings.Repeat("horse ", MaxPasswordLength), 0, ErrLong},
+	}
+	for i, v := range vectors {
+		words, err := DefaultPolicy.CheckPassphrase([]byte(v.s))
+		if words != v.words || err != v.err {
+			t.Errorf("%d: expected %d, %v, got %d, %v", i, v.words, v.err, words, err)
+		}
+	}
+}
+
+func TestRandomness(t *testing.T) {
+	bits, err := DefaultPolicy.Randomness([]byte("7304"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 4 * math.Log2(10); math.Abs(bits-want) > 1e-9 {
+		t.Errorf("expected %f bits, got %f", want, bits)
+	}
+	pass := []byte("dw1lIojbTBrq/gii")
+	r := DefaultPolicy.CheckResult(pass, nil, nil)
+	if bits, _ := DefaultPolicy.Randomness(pass); int(bits) != r.ApproxEntropy {
+		t.Errorf("expected %d bits, got %f", r.ApproxEntropy, bits)
+	}
+	for _, s := range []string{"", "a\x00b"} {
+		if _, err := DefaultPolicy.Randomness([]byte(s)); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestCheckWithNames(t *testing.T) {
+	pol := *DefaultPolicy
+	good := []byte("Mooseaxon#7Tq")
+	if err := pol.CheckWithNames(good, nil, "dmitry", "", "Dmitry Chestnykh"); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+	for _, names := range [][]string{
+		{"mooseaxon"},
+		{"dmitry", "mooseaxon"},
+		{"", "dmitry", "Moose Axon", "mooseaxon"},
+	} {
+		if err := pol.CheckWithNames(good, nil, names...); err != ErrPersonal {
+			t.Errorf("%q: expected ErrPersonal, got %v", names, err)
+		}
+	}
+	if err := pol.CheckWithNames(good, nil, "dmitry", "a\x00b"); err != ErrNul {
+		t.Errorf("expected ErrNul, got %v", err)
+	}
+	pol.ForbidUsername = true
+	if err := pol.CheckWithNames([]byte("dw1lIojbTBrq/gii1Mzf max"), nil, "dmitry", "max"); err != ErrPersonal {
+		t.Errorf("expected ErrPersonal with ForbidUsername, got %v", err)
+	}
+}
+
+func TestStillValid(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.OnReject = func(Reason) { t.Error("OnReject called") }
+	if !pol.StillValid([]byte("Mooseaxon#7Tq"), []byte("dmitry")) {
+		t.Error("expected valid password")
+	}
+	if pol.StillValid([]byte("Mooseaxon#7Tq"), []byte("mooseaxon")) {
+		t.Error("expected invalid password based on user name")
+	}
+	pol.Min = [5]int{Disabled, 24, 11, 16, 16}
+	if pol.StillValid([]byte("Mooseaxon#7Tq"), []byte("dmitry")) {
+		t.Error("expected invalid password after tightening policy")
+	}
+}
+
+func TestCheckUsers(t *testing.T) {
+	good := []byte("Mooseaxon#7Tq")
+	for _, v := range []struct {
+		current, previous string
+		err               error
+	}{
+		{"dmitry", "", nil},
+		{"dmitry", "max", nil},
+		{"mooseaxon", "dmitry", ErrPersonal},
+		{"dmitry", "mooseaxon", ErrPersonal},
+	} {
+		if err := DefaultPolicy.CheckUsers(good, nil, v.current, v.previous); err != v.err {
+			t.Errorf("%q, %q: expected %v, got %v", v.current, v.previous, v.err, err)
+		}
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	pol := *DefaultPolicy
+	pol.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	pol.Check([]byte("pass1"), []byte("secret-old"), []byte("someuser"))
+	pol.Check([]byte("dw1lIojbTBrq/gii"), nil, nil)
+	out := buf.String()
+	for _, s := range []string{"accepted=false reason=short", "accepted=true reason=none"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("log doesn't contain %q: %s", s, out)
+		}
+	}
+	for _, s := range []string{"pass1", "secret-old", "someuser", "dw1lIojbTBrq"} {
+		if strings.Contains(out, s) {
+			t.Errorf("log contains checked data %q: %s", s, out)
+		}
+	}
+	buf.Reset()
+	pol.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+	pol.Check([]byte("pass1"), nil, nil)
+	if buf.Len() != 0 {
+		t.Errorf("unexpected log output at info level: %s", buf.String())
+	}
+}
+
+func TestInit(t *testing.T) {
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckFunc(t *testing.T) {
+	for _, s := range []string{"short", "dw1lIojbTBrq/gii"} {
+		if err, expected := Check([]byte(s), nil, nil), DefaultPolicy.Check([]byte(s), nil, nil); err != expected {
+			t.Errorf("%q: expected %v, got %v", s, expected, err)
+		}
+	}
+}
+
+func TestCheckString(t *testing.T) {
+	for _, v := range []struct{ new, old, user string }{
+		{"", "", ""},
+		{"dw1lIojbTBrq/gii", "", ""},
+		{"dw1lIojbTBrq/gii", "dw1lIojbTBrq/gii", ""},
+		{"Dmitry1lIojb", "", "dmitry"},
+	} {
+		expected := DefaultPolicy.Check([]byte(v.new), bytesOrNil(v.old), bytesOrNil(v.user))
+		if err := DefaultPolicy.CheckString(v.new, v.old, v.user); err != expected {
+			t.Errorf("%q, %q, %q: expected %v, got %v", v.new, v.old, v.user, expected, err)
+		}
+	}
+}
+
+func TestCheckUTF16(t *testing.T) {
+	for _, v := range []struct {
+		new, old, user string
+	}{
+		{"", "", ""},
+		{"dw1lIojbTBrq/gii", "", ""},
+		{"dw1lIojbTBrq/gii", "dw1lIojbTBrq/gii", ""},
+		{"Dmitry1lIojb", "", "dmitry"},
+		{"I\u2764\ufe0fNY\U0001f5fd2024!", "", ""}, // surrogate pair
+		{"a\x00b", "", ""},
+	} {
+		expected := DefaultPolicy.Check([]byte(v.new), bytesOrNil(v.old), bytesOrNil(v.user))
+		toUTF16 := func(s string) []uint16 {
+			if s == "" {
+				return nil
+			}
+			return utf16.Encode([]rune(s))
+		}
+		if err := DefaultPolicy.CheckUTF16(utf16.Encode([]rune(v.new)), toUTF16(v.old), toUTF16(v.user)); err != expected {
+			t.Errorf("%q, %q, %q: expected %v, got %v", v.new, v.old, v.user, expected, err)
+		}
+	}
+	// An unpaired surrogate is replaced with U+FFFD.
+	invalid := append(utf16.Encode([]rune("dw1lIojbTBrq/gii")), 0xd800)
+	if b := utf16Bytes(invalid); string(b) != "dw1lIojbTBrq/gii\ufffd" {
+		t.Errorf("unexpected conversion %q", b)
+	}
+	if err := DefaultPolicy.CheckUTF16(invalid, nil, nil); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+}
+
+func TestCheckAgainstHashes(t *testing.T) {
+	history := map[string]bool{"dw1lIojbTBrq/gii": true}
+	calls := 0
+	compare := func(candidate []byte) bool {
+		calls++
+		return history[string(candidate)]
+	}
+	if err := DefaultPolicy.CheckAgainstHashes([]byte("dw1lIojbTBrq/gii"), compare); err != ErrSame {
+		t.Errorf("expected ErrSame, got %v", err)
+	}
+	if err := DefaultPolicy.CheckAgainstHashes([]byte("Ncc1701!enterprise"), compare); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+	if err := DefaultPolicy.CheckAgainstHashes([]byte("short"), compare); err != ErrShort {
+		t.Errorf("expected ErrShort, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls to compare, got %d", calls)
+	}
+}
+
+func TestFirstAcceptable(t *testing.T) {
+	candidates := [][]byte{[]byte("short"), []byte("dw1lIojbTBrq/gii"), []byte("Ncc1701!enterprise")}
+	if i, err := DefaultPolicy.FirstAcceptable(candidates, nil, nil); i != 1 || err != nil {
+		t.Errorf("expected 1, nil, got %d, %v", i, err)
+	}
+	if i, err := DefaultPolicy.FirstAcceptable(candidates[:1], nil, nil); i != -1 || err != ErrShort {
+		t.Errorf("expected -1, ErrShort, got %d, %v", i, err)
+	}
+	if i, err := DefaultPolicy.FirstAcceptable(nil, nil, nil); i != -1 || err != ErrEmpty {
+		t.Errorf("expected -1, ErrEmpty, got %d, %v", i, err)
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	config := "min=disabled,16,12,10,8 max=40 similar=deny reversed=deny"
+	p, err := NewPolicy(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := MustParsePolicy(config)
+	if p.String() != q.String() {
+		t.Errorf("NewPolicy: got %q, expected %q", p, q)
+	}
+	for _, v := range []struct{ new, old string }{
+		{"short", ""},
+		{"dw1lIojbTBrq/gii", ""},
+		{"dw1lIojbTBrq/gii", "dw1lIojbTBrq/gii"},
+		{"iig/qrBTbjoIl1wd", "dw1lIojbTBrq/gii"},
+	} {
+		if e1, e2 := p.Check([]byte(v.new), []byte(v.old), nil), q.Check([]byte(v.new), []byte(v.old), nil); e1 != e2 {
+			t.Errorf("%q, %q: got %v, expected %v", v.new, v.old, e1, e2)
+		}
+	}
+	if p.CheckSameOnly([]byte("iig/qrBTbjoIl1wd"), []byte("dw1lIojbTBrq/gii"), nil) != nil {
+		t.Errorf("CheckSameOnly rejected reversed password")
+	}
+	for _, c := range []string{
+		"min=8,16,12,10,8",
+		"min=disabled,8,9,10,11",
+		"min=disabled,24,11,8,7 max=6",
+		"min=-1,-1,-1,-1,-1",
+		"match=-1",
+		"passphrase=3 min=disabled,24,22,8,7 max=20",
+		"passphrase=6 wordlen=4 max=24",
+		"garbage",
+	} {
+		if _, err := NewPolicy(c); err == nil {
+			t.Errorf("%q: expected error", c)
+		}
+	}
+	if err := DefaultPolicy.Validate(); err != nil {
+		t.Errorf("DefaultPolicy: %v", err)
+	}
+}
+
+func TestConstantTimeSame(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.ConstantTimeSame = true
+	old := []byte("dw1lIojbTBrq/gii")
+	for _, v := range []struct {
+		new string
+		err error
+	}{
+		{"dw1lIojbTBrq/gii", ErrSame},
+		{" DW1LIOJBTBRQ/GII", ErrSame},
+		{"3wlIdAe/2v1xsQmybHU", nil},
+	} {
+		if err := pol.Check([]byte(v.new), old, nil); err != v.err {
+			t.Errorf("%q: expected %v, got %v", v.new, v.err, err)
+		}
+	}
+	if s := pol.DebugParams(); !strings.Contains(s, " skip_same=1 ") {
+		t.Errorf("expected passwdqc comparison to be skipped, got %q", s)
+	}
+	// passwdqc truncates passwords if Max is 8.
+	pol.Min = [5]int{8, 8, 8, 8, 8}
+	pol.Max = 8
+	old = []byte("Kx#mLqWp")
+	pass := []byte("Kx#mLqWpZ2x!")
+	if err := pol.Check(pass, old, nil); err != ErrSame {
+		t.Errorf("expected ErrSame for truncated password, got %v", err)
+	}
+	if errs := pol.CheckAll(pass, old, nil); !reflect.DeepEqual(errs, []error{ErrSame}) {
+		t.Errorf("CheckAll: expected ErrSame for truncated password, got %v", errs)
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	pol := *DefaultPolicy
+	if c := pol.Canonical([]byte(" \tPassWord1 \n")); string(c) != "password1" {
+		t.Errorf("unexpected canonical form %q", c)
+	}
+	old := []byte("dw1lIojbTBrq/gii")
+	for _, s := range []string{"dw1lIojbTBrq/gii ", "DW1LIOJBTBRQ/GII", "\tDw1lIojbTBrq/gii"} {
+		if err := pol.Check([]byte(s), old, nil); err != ErrSame {
+			t.Errorf("%q: expected ErrSame, got %v", s, err)
+		}
+		if errs := pol.CheckAll([]byte(s), old, nil); !containsError(errs, ErrSame) || containsError(errs, ErrSimilar) {
+			t.Errorf("%q: expected ErrSame without ErrSimilar from CheckAll, got %v", s, errs)
+		}
+	}
+	pol.CaseSensitive = true
+	if c := pol.Canonical([]byte(" PassWord1")); string(c) != "PassWord1" {
+		t.Errorf("unexpected canonical form %q", c)
+	}
+	if err := pol.Check([]byte("DW1LIOJBTBRQ/GII"), old, nil); err == ErrSame {
+		t.Errorf("unexpected ErrSame with case-sensitive policy")
+	}
+}
+
+func TestCheckSameOnly(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.DenyReversed = true
+	old := []byte("Xq9#level-zT7!")
+	for _, s := range []string{"Xq9#level-zT8!", "!7Tz-level#9qX"} {
+		if err := pol.Check([]byte(s), old, nil); err != ErrSimilar {
+			t.Errorf("%q: expected ErrSimilar from Check, got %v", s, err)
+		}
+		if err := pol.CheckSameOnly([]byte(s), old, nil); err != nil {
+			t.Errorf("%q: no error expected, got %v", s, err)
+		}
+	}
+	if err := pol.CheckSameOnly(old, old, nil); err != ErrSame {
+		t.Errorf("expected ErrSame, got %v", err)
+	}
+}
+
+func TestErrorsIs(t *testing.T) {
+	err := DefaultPolicy.Check([]byte("short"), nil, nil)
+	if !errors.Is(err, ErrShort) || errors.Is(err, ErrLong) {
+		t.Errorf("errors.Is failed for %v", err)
+	}
+	wrapped := fmt.Errorf("setting password: %w", err)
+	if !errors.Is(wrapped, ErrShort) {
+		t.Errorf("errors.Is failed for %v", wrapped)
+	}
+	var e *Error
+	if !errors.As(wrapped, &e) || e.Reason() != ReasonShort {
+		t.Errorf("errors.As failed for %v", wrapped)
+	}
+	for _, e := range AllReasons() {
+		if e.Unwrap() != nil {
+			t.Errorf("%s: sentinel wraps %v", e.Reason(), e.Unwrap())
+		}
+	}
+}
+
+func TestReasonText(t *testing.T) {
+	for r := ReasonNone; r <= ReasonDate; r++ {
+		b, err := r.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != r.String() {
+			t.Errorf("%d: expected %q, got %q", r, r.String(), b)
+		}
+		var decoded Reason
+		if err := decoded.UnmarshalText(b); err != nil || decoded != r {
+			t.Errorf("%q: expected %d, got %d (%v)", b, r, decoded, err)
+		}
+	}
+	if b, _ := Reason(-1).MarshalText(); string(b) != "unknown" {
+		t.Errorf("expected unknown for invalid reason, got %q", b)
+	}
+	var r Reason
+	if err := r.UnmarshalText([]byte("whatever")); err == nil {
+		t.Error("expected error for unknown reason code")
+	}
+	b, err := json.Marshal(DefaultPolicy.CheckResult([]byte("pass"), nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"reason":"short"`) {
+		t.Errorf("expected reason code in JSON, got %s", b)
+	}
+}
+
+func TestErrorJSON(t *testing.T) {
+	for _, v := range []struct {
+		err      error
+		expected string
+	}{
+		{ErrShort, `{"reason":"short","message":"too short"}`},
+		{ErrNoDigit, `{"reason":"nodigit","message":"must contain a digit"}`},
+		{errorForReason("some new reason"), `{"reason":"unknown","message":"some new reason"}`},
+		{&Error{desc: "odd"}, `{"reason":"unknown","message":"odd"}`},
+	} {
+		b, err := json.Marshal(v.err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != v.expected {
+			t.Errorf("expected %s, got %s", v.expected, b)
+		}
+	}
+}
+
+func TestErrorForReason(t *testing.T) {
+	for reason, expected := range errorsByReason {
+		// Simulate a reason returned in a differently allocated string.
+		copied := string([]byte(reason))
+		if err := errorForReason(copied); err != expected {
+			t.Errorf("%q: expected %v, got %v", reason, expected, err)
+		}
+	}
+	if err := errorForReason(reasonShort + "\n"); err != ErrShort {
+		t.Errorf("expected ErrShort for reason with trailing newline, got %v", err)
+	}
+	err := errorForReason("some new reason")
+	var e *Error
+	if !errors.As(err, &e) || e.Reason() != ReasonUnknown || !errors.Is(err, ErrFailed) {
+		t.Errorf("unexpected error for unknown reason: %#v", err)
+	}
+	if err.Error() != "passwordcheck: some new reason" {
+		t.Errorf("unexpected error message %q", err)
+	}
+}
+
+func TestClassCounts(t *testing.T) {
+	digits, lower, upper, other, nonascii := DefaultPolicy.ClassCounts([]byte("Pass w0rd!\u00e9"))
+	if digits != 1 || lower != 6 || upper != 1 || other != 2 || nonascii != 2 {
+		t.Errorf("unexpected counts: %d, %d, %d, %d, %d", digits, lower, upper, other, nonascii)
+	}
+	for _, pw := range [][]byte{[]byte("Pass\x00w0rd"), bytes.Repeat([]byte("a"), MaxPasswordLength+1)} {
+		if digits, lower, upper, other, nonascii := DefaultPolicy.ClassCounts(pw); digits+lower+upper+other+nonascii != 0 {
+			t.Errorf("expected no counts for invalid password, got %d, %d, %d, %d, %d", digits, lower, upper, other, nonascii)
+		}
+	}
+}
+
+func TestNonASCIIAsLetters(t *testing.T) {
+	p := MustParsePolicy("nonascii=letters")
+	for _, v := range []struct {
+		password                              string
+		digits, lower, upper, other, nonascii int
+	}{
+		{"\u00e9lan", 0, 4, 0, 0, 0},
+		{"\u00c9lan", 0, 3, 1, 0, 0},
+		{"\u5bc6\u7801", 0, 2, 0, 0, 0},
+		{"\u0416\u0438\u0437\u043d\u044c 42", 2, 4, 1, 1, 0},
+	} {
+		digits, lower, upper, other, nonascii := p.ClassCounts([]byte(v.password))
+		if digits != v.digits || lower != v.lower || upper != v.upper || other != v.other || nonascii != v.nonascii {
+			t.Errorf("%q: unexpected counts: %d, %d, %d, %d, %d", v.password, digits, lower, upper, other, nonascii)
+		}
+	}
+	// Upper-case non-ASCII letters add a class, so shorter passwords are
+	// accepted, and lower-case ones don't.
+	for _, v := range []struct {
+		password     string
+		def, letters error
+	}{
+		{"k9wux\u00c9vqz", ErrSimpleShort, nil},
+		{"zm\u00d6bqtwrkj.v", ErrSimpleShort, nil},
+		{"k9wux\u00e9vqz", ErrSimpleShort, ErrSimpleShort},
+		{"\u5bc6\u7801\u5f88\u5b89\u5168\u5417", ErrSimpleShort, ErrSimpleShort},
+	} {
+		if err := DefaultPolicy.CheckString(v.password, "", ""); err != v.def {
+			t.Errorf("%q: default: expected %v, got %v", v.password, v.def, err)
+		}
+		if err := p.CheckString(v.password, "", ""); err != v.letters {
+			t.Errorf("%q: letters: expected %v, got %v", v.password, v.letters, err)
+		}
+	}
+}
+
+func TestMinForClasses(t *testing.T) {
+	for n, want := range map[int]int{1: Disabled, 2: 24, Passphrase: 11, 3: 8, 4: 7} {
+		if got := DefaultPolicy.MinForClasses(n); got != want {
+			t.Errorf("%d: expected %d, got %d", n, want, got)
+		}
+	}
+	p, err := NewPolicy("min=disabled,24,11,8,7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetMinForClasses(4, 12)
+	p.SetMinForClasses(Passphrase, Disabled)
+	if p.Min != [5]int{Disabled, 24, Disabled, 8, 12} {
+		t.Errorf("unexpected Min: %v", p.Min)
+	}
+	if err := p.CheckString("dw1lIojbTB/", "", ""); err != ErrShort {
+		t.Errorf("expected %v, got %v", ErrShort, err)
+	}
+	for _, n := range []int{0, 5, -2} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%d: expected panic", n)
+				}
+			}()
+			DefaultPolicy.MinForClasses(n)
+		}()
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	for _, v := range []struct {
+		password, username string
+		want               [5]bool
+	}{
+		{"dw1lIojbTBrq/gii", "", [5]bool{true, true, true, true, true}},
+		{"", "", [5]bool{false, false, true, true, true}},
+		{"Jd7#", "", [5]bool{false, false, true, true, true}},
+		{"password", "", [5]bool{false, false, false, true, true}},
+		{"P@ssw0rd", "", [5]bool{true, true, false, true, true}},
+		{"1qaz2wsx3edc", "", [5]bool{true, true, true, false, true}},
+		{"johnsmith#42Z", "johnsmith", [5]bool{true, true, true, true, false}},
+	} {
+		var got [5]bool
+		got[0], got[1], got[2], got[3], got[4] = DefaultPolicy.Satisfies([]byte(v.password), bytesOrNil(v.username))
+		if got != v.want {
+			t.Errorf("%q: expected %v, got %v", v.password, v.want, got)
+		}
+	}
+}
+
+func TestCheckMigration(t *testing.T) {
+	stricter := MustParsePolicy("min=disabled,24,12,12,12")
+	rejected := 0
+	stricter.OnReject = func(Reason) { rejected++ }
+	for _, v := range []struct {
+		password  string
+		warn, err error
+	}{
+		{"dw1lIojbTBrq/gii", nil, nil},
+		{"Mooseax#7Tq", ErrShort, nil},
+		{"sh0rt", nil, ErrShort},
+	} {
+		warn, err := DefaultPolicy.CheckMigration(stricter, []byte(v.password), nil, nil)
+		if warn != v.warn || err != v.err {
+			t.Errorf("%q: expected %v, %v, got %v, %v", v.password, v.warn, v.err, warn, err)
+		}
+	}
+	if rejected != 0 {
+		t.Errorf("OnReject of new policy called %d times", rejected)
+	}
+}
+
+func TestEffectiveLength(t *testing.T) {
+	permit := MustParsePolicy("similar=permit dictionary=skip")
+	skip := MustParsePolicy("dictionary=skip")
+	negative := *DefaultPolicy
+	negative.MatchLength = -1
+	for _, v := range []struct {
+		p                       *Policy
+		password, old, username string
+		length                  int
+	}{
+		{DefaultPolicy, "", "", "", 0},
+		{DefaultPolicy, "a\x00b", "", "", 0},
+		{DefaultPolicy, "dw1lIojbTBrq/gii", "", "", 16},
+		{DefaultPolicy, "johnsmith#42Zq", "", "johnsmith", 8}, // removed, 3 credited
+		{DefaultPolicy, "Password2!xyzQ", "Password2", "", 8},
+		{permit, "Password2!xyzQ", "Password2", "", 14},
+		{DefaultPolicy, "correct horse battery staple", "", "", 25},
+		{skip, "correct horse battery staple", "", "", 28},
+		{&negative, "dw1lIojbTBrq/gii", "", "", 0}, // passwdqc fails
+	} {
+		if n := v.p.EffectiveLength([]byte(v.password), bytesOrNil(v.old), bytesOrNil(v.username)); n != v.length {
+			t.Errorf("%q: expected %d, got %d", v.password, v.length, n)
+		}
+	}
+}
+
+func TestStrengthCategory(t *testing.T) {
+	for _, v := range []struct {
+		password string
+		category int
+	}{
+		{"", 0},
+		{"a\x00b", 0},
+		{"1234", 0},
+		{"abc12d", 1},       // 6*log2(36) = 31 bits
+		{"abcdefg1X", 2},    // 9*log2(62) = 53.6 bits
+		{"dw1lIojbTBrq", 3}, // 12*log2(62) = 71.5 bits
+		{"dw1lIojbTBrq/gii", 4},
+	} {
+		if c := DefaultPolicy.StrengthCategory([]byte(v.password)); c != v.category {
+			t.Errorf("%q: expected %d, got %d", v.password, v.category, c)
+		}
+	}
+}
+
+func TestRequiredLength(t *testing.T) {
+	for _, v := range []struct {
+		password string
+		length   int
+	}{
+		{"", 0},
+		{"12345", 0},
+		{"abcdef", 0},
+		{"abc12d", 24},
+		{"abcDef", 24},
+		{"Abcdef", 0}, // leading upper-case letter doesn't count
+		{"abc1D", 8},
+		{"abc1D!", 7},
+		{"correct horse battery", 11},
+		{"correct horse", 24},
+		{"abc1D!\x00", 0},
+		{strings.Repeat("abc1D!", MaxPasswordLength/6+1), 0},
+	} {
+		if n := DefaultPolicy.RequiredLength([]byte(v.password)); n != v.length {
+			t.Errorf("%q: expected %d, got %d", v.password, v.length, n)
+		}
+	}
+	pol := *DefaultPolicy
+	pol.Min[0] = 30
+	if n := pol.RequiredLength([]byte("12345")); n != 30 {
+		t.Errorf("expected 30, got %d", n)
+	}
+}
+
+func TestEstimateCrackTime(t *testing.T) {
+	// 4 digits: 10^4/2 guesses.
+	if d := DefaultPolicy.EstimateCrackTime([]byte("7304"), 1000); d.Round(time.Millisecond) != 5*time.Second {
+		t.Errorf("expected 5s, got %s", d)
+	}
+	if d := DefaultPolicy.EstimateCrackTime(nil, 1000); d != 0 {
+		t.Errorf("expected 0 for empty password, got %s", d)
+	}
+	long := []byte("dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU")
+	if d := DefaultPolicy.EstimateCrackTime(long, 1e12); d != math.MaxInt64 {
+		t.Errorf("expected maximum duration, got %s", d)
+	}
+	if d := DefaultPolicy.EstimateCrackTime([]byte("7304"), 0); d != math.MaxInt64 {
+		t.Errorf("expected maximum duration for zero guesses per second, got %s", d)
+	}
+	for _, pw := range [][]byte{[]byte("7304\x00"), bytes.Repeat([]byte("7"), MaxPasswordLength+1)} {
+		if d := DefaultPolicy.EstimateCrackTime(pw, 1000); d != 0 {
+			t.Errorf("expected 0 for invalid password, got %s", d)
+		}
+	}
+}
+
+func TestReasonString(t *testing.T) {
+	if s := ErrSimpleShort.Reason().String(); s != "simpleshort" {
+		t.Errorf("expected %q, got %q", "simpleshort", s)
+	}
+	if s := Reason(-1).String(); s != "Reason(-1)" {
+		t.Errorf("expected %q, got %q", "Reason(-1)", s)
+	}
+}
+
+func FuzzParsePolicy(f *testing.F) {
+	f.Add(DefaultPolicy.String())
+	f.Add("min=10,disabled,111,1222,13\nmax=12345\npassphrase=9876\nmatch=1\nsimilar=permit")
+	f.Add("max=123 blah=1")
+	f.Fuzz(func(t *testing.T, s string) {
+		p, err := ParsePolicy(s)
+		if err != nil {
+			return
+		}
+		q, err := ParsePolicy(p.String())
+		if err != nil {
+			t.Fatalf("failed to parse %q: %s", p.String(), err)
+		}
+		if !reflect.DeepEqual(p, q) {
+			t.Errorf("round trip of %q: expected %v, got %v", s, p, q)
+		}
+	})
+}
+
+func FuzzCheck(f *testing.F) {
+	f.Add([]byte("password1"), []byte("password2"), []byte("brewery"))
+	f.Add([]byte("dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU"), []byte(nil), []byte(nil))
+	f.Add([]byte("abc\x00longrandomstuff"), []byte("\x00"), []byte("\xff\xfe"))
+	f.Fuzz(func(t *testing.T, newPassword, oldPassword, username []byte) {
+		err := DefaultPolicy.Check(newPassword, oldPassword, username)
+		if len(bytes.TrimSpace(newPassword)) == 0 {
+			if err != ErrEmpty {
+				t.Errorf("%q: expected ErrEmpty, got %v", newPassword, err)
+			}
+			return
+		}
+		nul := bytes.IndexByte(newPassword, 0) >= 0 ||
+			bytes.IndexByte(oldPassword, 0) >= 0 ||
+			bytes.IndexByte(username, 0) >= 0
+		if nul != (err == ErrNul) {
+			t.Errorf("%q, %q, %q: unexpected result %v", newPassword, oldPassword, username, err)
+		}
+	})
+}
+
+var benchPassword, benchOldPassword, benchUsername = "dw1lIojbTBrq/gii1MzfZVL8", "3wlIdAe/2v1xsQmybHU", "dmitry"
+
+func BenchmarkCheckBytes(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		// Convert inside the loop to compare with BenchmarkCheckString.
+		if err := DefaultPolicy.Check([]byte(benchPassword), []byte(benchOldPassword), []byte(benchUsername)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCheckString(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := DefaultPolicy.CheckString(benchPassword, benchOldPassword, benchUsername); err != nil {
+			b.Fatal(err)
 		}
 	}
 }
@@ -0,0 +1,49 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+// Package passwordchecktest provides helpers for testing password policies
+// created with package passwordcheck.
+package passwordchecktest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dchest/passwordcheck"
+)
+
+// Accepted is the reason to pass to AssertReason for passwords that must
+// be accepted.
+const Accepted = passwordcheck.ReasonNone
+
+// AssertReason checks the new password with the policy p, without the old
+// password and user name, and reports a test failure if the result doesn't
+// have the wanted reason, which is Accepted if the password must comply
+// with the policy.
+func AssertReason(t testing.TB, p *passwordcheck.Policy, password string, want passwordcheck.Reason) {
+	t.Helper()
+	got := reasonOf(p.Check([]byte(password), nil, nil))
+	if got == want {
+		return
+	}
+	switch {
+	case want == Accepted:
+		t.Errorf("password %q: rejected with reason %s, want accepted", password, got)
+	case got == Accepted:
+		t.Errorf("password %q: accepted, want rejected with reason %s", password, want)
+	default:
+		t.Errorf("password %q: rejected with reason %s, want %s", password, got, want)
+	}
+}
+
+// reasonOf returns the reason of the error returned by Check.
+func reasonOf(err error) passwordcheck.Reason {
+	if err == nil {
+		return Accepted
+	}
+	var e *passwordcheck.Error
+	if errors.As(err, &e) {
+		return e.Reason()
+	}
+	return passwordcheck.ReasonUnknown
+}
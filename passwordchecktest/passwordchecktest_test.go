@@ -0,0 +1,45 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordchecktest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dchest/passwordcheck"
+)
+
+// recorder records test failures instead of reporting them.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertReason(t *testing.T) {
+	p := passwordcheck.DefaultPolicy
+	AssertReason(t, p, "dw1lIojbTBrq/gii", Accepted)
+	AssertReason(t, p, "short", passwordcheck.ReasonShort)
+
+	for _, v := range []struct {
+		password string
+		want     passwordcheck.Reason
+		message  string
+	}{
+		{"short", Accepted, `password "short": rejected with reason short, want accepted`},
+		{"dw1lIojbTBrq/gii", passwordcheck.ReasonShort, `password "dw1lIojbTBrq/gii": accepted, want rejected with reason short`},
+		{"short", passwordcheck.ReasonWord, `password "short": rejected with reason short, want word`},
+	} {
+		r := &recorder{TB: t}
+		AssertReason(r, p, v.password, v.want)
+		if len(r.errors) != 1 || r.errors[0] != v.message {
+			t.Errorf("expected failure %q, got %q", v.message, r.errors)
+		}
+	}
+}
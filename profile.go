@@ -0,0 +1,76 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Profile is personal information about a user that passwords must not be
+// based on.
+type Profile struct {
+	Username string   // login name
+	Name     string   // full or display name
+	Email    string   // email address
+	Aliases  []string // other names, such as legacy logins or nicknames
+
+	// BirthYear is the year of birth, such as 1985, or 0 if unknown.
+	BirthYear int
+
+	// BirthDate is the date of birth, or the zero time if unknown. If it's
+	// set, BirthYear defaults to its year.
+	BirthDate time.Time
+}
+
+// names returns the names from the profile to match passwords against.
+func (pr *Profile) names() []string {
+	names := []string{pr.Username, pr.Name}
+	if pr.Email != "" {
+		names = append(names, pr.Email)
+		if i := strings.LastIndexByte(pr.Email, '@'); i >= 0 {
+			names = append(names, pr.Email[:i], pr.Email[i+1:])
+		}
+	}
+	return append(names, pr.Aliases...)
+}
+
+// dates returns the numeric representations of the birth date, such as
+// "1985", "0312", and "1203" for March 12, 1985.
+func (pr *Profile) dates() []string {
+	var dates []string
+	year := pr.BirthYear
+	if !pr.BirthDate.IsZero() {
+		if year == 0 {
+			year = pr.BirthDate.Year()
+		}
+		m, d := int(pr.BirthDate.Month()), pr.BirthDate.Day()
+		dates = append(dates, fmt.Sprintf("%02d%02d", m, d), fmt.Sprintf("%02d%02d", d, m))
+	}
+	if year > 0 {
+		dates = append(dates, fmt.Sprintf("%04d", year))
+	}
+	return dates
+}
+
+// CheckWithProfile is like Check, but matches the new password against the
+// personal information in the profile: the user name, full name, email
+// address, and aliases are matched as by CheckWithNames, and passwords
+// containing the birth year or the month and day of the birth date in
+// either order, such as "John1985" or "rose0312", are rejected with
+// ErrPersonal.
+func (p *Policy) CheckWithProfile(newPassword, oldPassword []byte, profile Profile) error {
+	err := p.checkWithNames(newPassword, oldPassword, profile.names())
+	if err == nil {
+		for _, d := range profile.dates() {
+			if bytes.Contains(newPassword, []byte(d)) {
+				err = ErrPersonal
+				break
+			}
+		}
+	}
+	return p.rejected(err)
+}
@@ -0,0 +1,39 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckWithProfile(t *testing.T) {
+	profile := Profile{
+		Username:  "jsmith",
+		Name:      "John Smith",
+		Email:     "mooseaxon@example.com",
+		Aliases:   []string{"tigerlily"},
+		BirthDate: time.Date(1985, time.March, 12, 0, 0, 0, 0, time.UTC),
+	}
+	if err := DefaultPolicy.CheckWithProfile([]byte("Vq#7Tk9!wz"), nil, profile); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+	for _, v := range []string{
+		"Vq#7Tk9!wz1985",
+		"Vq#7Tk9!wz0312",
+		"1203Vq#7Tk9!wz",
+		"Mooseaxon#7Tq",
+		"Tigerlily#7Tq",
+	} {
+		if err := DefaultPolicy.CheckWithProfile([]byte(v), nil, profile); err != ErrPersonal {
+			t.Errorf("%q: expected ErrPersonal, got %v", v, err)
+		}
+	}
+	if err := DefaultPolicy.CheckWithProfile([]byte("Vq#7Tk9!wz1986"), nil, Profile{BirthYear: 1986}); err != ErrPersonal {
+		t.Errorf("expected ErrPersonal for birth year, got %v", err)
+	}
+	if err := DefaultPolicy.CheckWithProfile([]byte("Vq#7Tk9!wz"), nil, Profile{Name: "a\x00b"}); err != ErrNul {
+		t.Errorf("expected ErrNul, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	mathrand "math/rand"
+)
+
+// randomBits is the default number of bits of randomness in generated
+// passphrases, as in passwdqc.
+const randomBits = 47
+
+// Limits for Policy's RandomBits, as in passwdqc.
+const (
+	minRandomBits = 24
+	maxRandomBits = 136
+)
+
+// separators are characters used to separate words in generated
+// passphrases, as in passwdqc.
+const separators = "-_!$&*+=23456789"
+
+// GenerateRandom returns a random passphrase consisting of words from the
+// passwdqc wordlist separated by random characters, with approximately 47
+// bits of randomness. Random bytes are read from crypto/rand.
+func GenerateRandom() (string, error) {
+	return GenerateRandomFrom(rand.Reader)
+}
+
+// GenerateRandomSeeded is like GenerateRandom, but uses a pseudorandom
+// generator from math/rand seeded with seed, so that it always returns the
+// same passphrase for the same seed, which is useful for tests and demos.
+//
+// The result is predictable by anyone who knows or guesses the seed. It is
+// NOT suitable for production use and must never be used to generate real
+// passwords; use GenerateRandom instead.
+func GenerateRandomSeeded(seed int64) (string, error) {
+	return GenerateRandomFrom(mathrand.New(mathrand.NewSource(seed)))
+}
+
+// GenerateRandomFrom is like GenerateRandom, but reads random bytes from r.
+//
+// Each word takes 3 bytes from r: 12 bits select a word from the list of 4096
+// words, 1 bit selects whether to capitalize it, and 4 bits select a
+// separator before the next word.
+func GenerateRandomFrom(r io.Reader) (string, error) {
+	return generateRandom(r, randomBits)
+}
+
+// GenerateRandom is like the GenerateRandom function, but generates
+// passphrases with RandomBits bits of randomness.
+func (p *Policy) GenerateRandom() (string, error) {
+	return generateRandom(rand.Reader, p.randomBits())
+}
+
+// GenerateRandomN returns n different passphrases generated like with
+// GenerateRandom, each of which complies with the policy, for offering
+// users a choice. Passphrases rejected by the policy are skipped. It
+// returns an error if random bytes cannot be read or if it cannot generate
+// enough compliant passphrases after many attempts, which happens with
+// policies rejecting most generated passphrases.
+func (p *Policy) GenerateRandomN(n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	out := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	bits := p.randomBits()
+	for attempts := 0; len(out) < n; attempts++ {
+		if attempts >= 100+10*n {
+			return nil, errors.New("passwordcheck: failed to generate passphrases complying with the policy")
+		}
+		s, err := generateRandom(rand.Reader, bits)
+		if err != nil {
+			return nil, err
+		}
+		if seen[s] || p.check([]byte(s), nil, nil) != nil {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// randomBits returns RandomBits or the default.
+func (p *Policy) randomBits() int {
+	if p.RandomBits == 0 {
+		return randomBits
+	}
+	return p.RandomBits
+}
+
+// generateRandom returns a random passphrase with at least the given
+// number of bits of randomness.
+func generateRandom(r io.Reader, bits int) (string, error) {
+	// Each word gives 13 bits and each separator gives 4 bits.
+	n := 1
+	for b := 13; b < bits; b += 17 {
+		n++
+	}
+	b := make([]byte, 3*n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	out := make([]byte, 0, n*(wordsetLengthMax+1))
+	for i := 0; i < n; i++ {
+		v := b[i*3:]
+		w := wordset4k[int(v[0])|int(v[1]&0x0f)<<8]
+		if v[1]&0x10 != 0 && isLower(w[0]) {
+			out = append(out, w[0]-('a'-'A'))
+			w = w[1:]
+		}
+		out = append(out, w...)
+		if i < n-1 {
+			out = append(out, separators[v[2]&0x0f])
+		}
+	}
+	return string(out), nil
+}
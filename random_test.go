@@ -0,0 +1,99 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestGenerateRandom(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		s, err := GenerateRandom()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := DefaultPolicy.Check([]byte(s), nil, nil); err != nil {
+			t.Errorf("generated passphrase %q rejected: %s", s, err)
+		}
+	}
+}
+
+func TestGenerateRandomSeeded(t *testing.T) {
+	s1, err := GenerateRandomSeeded(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, _ := GenerateRandomSeeded(1)
+	s3, _ := GenerateRandomSeeded(2)
+	if s1 != s2 {
+		t.Errorf("different passphrases for the same seed: %q, %q", s1, s2)
+	}
+	if s1 == s3 {
+		t.Errorf("same passphrase for different seeds: %q", s1)
+	}
+}
+
+func TestGenerateRandomFrom(t *testing.T) {
+	r := bytes.NewReader([]byte{
+		0x00, 0x10, 0x00, // "Adam", capitalized, '-'
+		0x05, 0x00, 0x0f, // "Amazon", '9'
+		0xff, 0x1f, 0x00, // "zone", capitalized
+	})
+	s, err := GenerateRandomFrom(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Adam-Amazon9Zone"
+	if s != expected {
+		t.Errorf("expected %q, got %q", expected, s)
+	}
+	if _, err := GenerateRandomFrom(bytes.NewReader([]byte{1, 2, 3})); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestPolicyGenerateRandom(t *testing.T) {
+	for _, v := range []struct{ bits, words int }{{0, 3}, {24, 2}, {47, 3}, {85, 6}} {
+		p := *DefaultPolicy
+		p.RandomBits = v.bits
+		s, err := p.GenerateRandom()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st := passwordStats([]byte(s), 0, false); st.words != v.words {
+			t.Errorf("%d bits: expected %d words, got %q", v.bits, v.words, s)
+		}
+	}
+}
+
+func TestGenerateRandomN(t *testing.T) {
+	s, err := DefaultPolicy.GenerateRandomN(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 20 {
+		t.Fatalf("expected 20 passphrases, got %d", len(s))
+	}
+	seen := make(map[string]bool)
+	for _, v := range s {
+		if seen[v] {
+			t.Errorf("duplicate passphrase %q", v)
+		}
+		seen[v] = true
+		if err := DefaultPolicy.Check([]byte(v), nil, nil); err != nil {
+			t.Errorf("generated passphrase %q rejected: %s", v, err)
+		}
+	}
+	if s, err := DefaultPolicy.GenerateRandomN(0); err != nil || len(s) != 0 {
+		t.Errorf("expected no passphrases, got %q, %v", s, err)
+	}
+	pol := *DefaultPolicy
+	pol.Max = 8
+	pol.Min = [5]int{Disabled, Disabled, Disabled, Disabled, Disabled}
+	if _, err := pol.GenerateRandomN(1); err == nil {
+		t.Error("expected error for policy rejecting all passphrases")
+	}
+}
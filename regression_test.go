@@ -0,0 +1,152 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"strings"
+	"testing"
+)
+
+// regressionVectors are passwords with the reasons for rejecting them with
+// DefaultPolicy, or ReasonNone for accepted ones, so that changes to the
+// bundled passwdqc code or to the checks don't silently change behavior.
+var regressionVectors = []struct {
+	password string
+	reason   Reason
+}{
+	// Passwords from testdata/passwords.txt.gz, some of them capitalized
+	// and with a symbol and digits appended, or joined into passphrases.
+	{"****", ReasonShort},
+	{"****#2024", ReasonSimpleShort},
+	{"**** 11112222 budgie", ReasonNone},
+	{"1955", ReasonShort},
+	{"515151", ReasonShort},
+	{"515151#2024", ReasonSimpleShort},
+	{"aileen", ReasonShort},
+	{"armada", ReasonShort},
+	{"Armada#2024", ReasonNone},
+	{"battle", ReasonShort},
+	{"battle belle doudou", ReasonNone},
+	{"bizkit", ReasonShort},
+	{"Bizkit#2024", ReasonNone},
+	{"brendan", ReasonSimpleShort},
+	{"cannabis", ReasonSimpleShort},
+	{"Cannabis#2024", ReasonNone},
+	{"chicago", ReasonSimpleShort},
+	{"condom", ReasonShort},
+	{"Condom#2024", ReasonNone},
+	{"condom cowboy heaven", ReasonNone},
+	{"daniel1", ReasonSimpleShort},
+	{"dixon", ReasonShort},
+	{"Dixon#2024", ReasonNone},
+	{"eight", ReasonShort},
+	{"ffffff1", ReasonSimpleShort},
+	{"Ffffff1#2024", ReasonNone},
+	{"fuaqz4", ReasonShort},
+	{"fuaqz4 gamecock love69", ReasonNone},
+	{"glover", ReasonShort},
+	{"Glover#2024", ReasonNone},
+	{"handsome", ReasonSimpleShort},
+	{"hongkong", ReasonSimpleShort},
+	{"Hongkong#2024", ReasonNone},
+	{"ishmael", ReasonSimpleShort},
+	{"jordie", ReasonShort},
+	{"Jordie#2024", ReasonNone},
+	{"jordie karaoke peavey", ReasonNone},
+	{"kombat", ReasonShort},
+	{"lizzard", ReasonSimpleShort},
+	{"Lizzard#2024", ReasonNone},
+	{"manman", ReasonShort},
+	{"mhine", ReasonShort},
+	{"Mhine#2024", ReasonNone},
+	{"mozart", ReasonShort},
+	{"mozart natasha1 sheeba", ReasonNone},
+	{"none1", ReasonShort},
+	{"None1#2024", ReasonNone},
+	{"papabear", ReasonSimpleShort},
+	{"pippin", ReasonShort},
+	{"Pippin#2024", ReasonNone},
+	{"punisher", ReasonSimpleShort},
+	{"redshift", ReasonSimpleShort},
+	{"Redshift#2024", ReasonNone},
+	{"redshift riffraff tyvugq", ReasonNone},
+	{"rushmore", ReasonSimpleShort},
+	{"series", ReasonShort},
+	{"Series#2024", ReasonNone},
+	{"skydive", ReasonSimpleShort},
+	{"spoiled", ReasonSimpleShort},
+	{"Spoiled#2024", ReasonNone},
+	{"sunflowe", ReasonSimpleShort},
+	{"sunflowe systems 737373", ReasonNone},
+	{"thelorax", ReasonSimpleShort},
+	{"Thelorax#2024", ReasonNone},
+	{"tripod", ReasonShort},
+	{"visitor", ReasonSimpleShort},
+	{"Visitor#2024", ReasonNone},
+	{"wolfen", ReasonShort},
+	// Common passwords and patterns.
+	{"password", ReasonSimpleShort},
+	{"Password1", ReasonSimpleShort},
+	{"P@ssw0rd", ReasonWord},
+	{"P@ssw0rd!2024", ReasonNone},
+	{"drowssap", ReasonSimpleShort},
+	{"qwertyuiop", ReasonSimpleShort},
+	{"1qaz2wsx3edc", ReasonSeq},
+	{"abcdef123456", ReasonSimpleShort},
+	{"Abcdef123456!", ReasonNone},
+	{"19841984", ReasonSimpleShort},
+	{"iloveyou", ReasonSimpleShort},
+	{"Il0v3y0u!", ReasonNone},
+	{"letmein123", ReasonSimpleShort},
+	{"monkey", ReasonShort},
+	{"dragon", ReasonShort},
+	{"trustno1", ReasonSimpleShort},
+	{"Tr0ub4dor&3", ReasonNone},
+	{"correct horse battery staple", ReasonNone},
+	{"correcthorsebatterystaple", ReasonSimple},
+	{"aaaaaaaaaaaaaaaaaaaaaaaaa", ReasonSimple},
+	{"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", ReasonSimple},
+	{"1111111111111111111111111", ReasonSimple},
+	{"ababababababababababababab", ReasonSimple},
+	{"!!!!!!!!!!!!!!!!!!!!!!!!!", ReasonSimple},
+	// Empty, blank, and NUL-containing passwords.
+	{"", ReasonEmpty},
+	{" ", ReasonEmpty},
+	{"\t \n", ReasonEmpty},
+	{"a\x00b", ReasonNul},
+	{"\x00", ReasonNul},
+	{"password\x00dw1lIojbTBrq/gii", ReasonNul},
+	// Non-ASCII characters, which passwdqc counts per byte.
+	{"\U0001f600\U0001f601\U0001f602\U0001f603", ReasonSimpleShort},
+	{"\U0001f600\U0001f600\U0001f600\U0001f600\U0001f600\U0001f600\U0001f600", ReasonSimple},
+	{"\U0001f525fire\U0001f525fire\U0001f525", ReasonSimpleShort},
+	{"I\u2764\ufe0fNY\U0001f5fd2024!", ReasonNone},
+	{"\u5bc6\u7801\u5f88\u5b89\u5168", ReasonSimpleShort},
+	{"Gr\u00fc\u00dfGott!42", ReasonNone},
+	// Random and other strong passwords of various lengths.
+	{"dw1lIojbTBrq/gii", ReasonNone},
+	{"dw1lIojb", ReasonNone},
+	{"Ncc1701!enterprise", ReasonNone},
+	{"Jd7#", ReasonShort},
+	{"Jd7#kQ2", ReasonNone},
+	{"Jd7#kQ2-", ReasonNone},
+	{"x9Lp2Wq!m", ReasonNone},
+	// Long passwords.
+	{strings.Repeat("dw1lIojbTBrq/gii", 64), ReasonNone}, // Max
+	{strings.Repeat("dw1lIojbTBrq/gii", 64) + "x", ReasonLong},
+	{strings.Repeat("Ab1!", MaxPasswordLength/4), ReasonLong}, // MaxPasswordLength
+	{strings.Repeat("Ab1!", MaxPasswordLength/4) + "x", ReasonLong},
+}
+
+func TestRegression(t *testing.T) {
+	for _, v := range regressionVectors {
+		reason := ReasonNone
+		if err := DefaultPolicy.Check([]byte(v.password), nil, nil); err != nil {
+			reason = err.(*Error).Reason()
+		}
+		if reason != v.reason {
+			t.Errorf("%.40q: expected %s, got %s", v.password, v.reason, reason)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"math"
+	"time"
+)
+
+// Result describes the outcome of checking a password.
+//
+// It contains only plain values, which don't refer to memory of passwdqc,
+// so it can be encoded as JSON, with Reason encoded as its code, such as
+// "short", or mapped to a protocol buffer message, with Reason as an
+// enumeration, for returning from a validation service.
+type Result struct {
+	OK            bool   `json:"ok"`            // password complies with the policy
+	Reason        Reason `json:"reason"`        // reason for rejection or ReasonNone
+	Message       string `json:"message"`       // error message or empty string
+	ApproxEntropy int    `json:"approxEntropy"` // approximate entropy in bits
+	IsPassphrase  bool   `json:"isPassphrase"`  // password has enough words to be a passphrase
+}
+
+// CheckResult is like Check, but returns a Result describing the outcome
+// along with some details about the password. Details are not computed
+// for passwords longer than MaxPasswordLength.
+func (p *Policy) CheckResult(newPassword, oldPassword, username []byte) Result {
+	var r Result
+	if err := p.Check(newPassword, oldPassword, username); err != nil {
+		r.Reason = err.(*Error).Reason()
+		r.Message = err.Error()
+	} else {
+		r.OK = true
+	}
+	if len(newPassword) > MaxPasswordLength {
+		return r
+	}
+	st := p.stats(newPassword)
+	r.ApproxEntropy = int(entropy(&st))
+	r.IsPassphrase = p.PassphraseWords > 0 && st.words >= p.PassphraseWords
+	return r
+}
+
+// CheckPassphrase checks the passphrase like Check without the old password
+// and user name, and also returns the number of words in it, counted as
+// passwdqc does with PassphraseMinWordLen taken into account, so that it
+// can be compared to PassphraseWords, for example, to show "3 of 4 words".
+//
+// Passphrases with too few words are checked as regular passwords, so they
+// are usually rejected with ErrWord, ErrSeq, or ErrSimpleShort, but strong
+// enough ones are accepted. Words are not counted in passphrases longer
+// than MaxPasswordLength, which are rejected with ErrLong.
+func (p *Policy) CheckPassphrase(passphrase []byte) (words int, err error) {
+	if len(passphrase) > MaxPasswordLength {
+		return 0, p.Check(passphrase, nil, nil)
+	}
+	st := p.stats(passphrase)
+	return st.words, p.Check(passphrase, nil, nil)
+}
+
+// Randomness returns the approximate number of bits of randomness in the
+// password, the same value as ApproxEntropy of CheckResult before rounding.
+//
+// passwdqc doesn't compute a single randomness figure when checking
+// passwords: instead, it compares the password length and the number of
+// different characters with the expectations for its number of character
+// classes given by Min. The returned value is the entropy of a random
+// password with the same length and number of character classes, as
+// passwdqc counts them, which is useful for comparing sample passwords
+// when tuning Min.
+//
+// It returns ErrEmpty for empty passwords, ErrNul for passwords containing
+// NUL bytes, and ErrLong for passwords longer than MaxPasswordLength.
+func (p *Policy) Randomness(password []byte) (float64, error) {
+	if err := statsError(password); err != nil {
+		return 0, err
+	}
+	st := p.stats(password)
+	return entropy(&st), nil
+}
+
+// statsError returns ErrEmpty, ErrNul, or ErrLong if the password is empty,
+// contains NUL bytes, or is longer than MaxPasswordLength, or nil if
+// passwdqc statistics can be computed for it.
+func statsError(password []byte) error {
+	switch {
+	case len(password) == 0:
+		return ErrEmpty
+	case hasNul(password):
+		return ErrNul
+	case len(password) > MaxPasswordLength:
+		return ErrLong
+	}
+	return nil
+}
+
+// ClassCounts returns the number of characters in the password in each
+// character class as passwdqc classifies them: ASCII digits, lower-case
+// letters, upper-case letters, other ASCII characters, and bytes of
+// non-ASCII characters, which passwdqc counts separately and treats as an
+// additional class only in some cases. With NonASCIIAsLetters, non-ASCII
+// characters are counted as lower-case or upper-case letters instead, one
+// per character, and nonascii is 0.
+//
+// When determining the number of classes, passwdqc doesn't count an
+// upper-case first letter or a digit at the end of the password, so a
+// class with a non-zero count may not contribute to it.
+//
+// All counts are 0 for passwords containing NUL bytes or longer than
+// MaxPasswordLength.
+func (p *Policy) ClassCounts(password []byte) (digits, lower, upper, other, nonascii int) {
+	if statsError(password) != nil {
+		return
+	}
+	st := p.stats(password)
+	return st.digits, st.lowers, st.uppers, st.others, st.unknowns
+}
+
+// RequiredLength returns the minimum length the password must have given
+// its current mix of character classes, as counted by passwdqc: the
+// smallest of the Min values for its number of classes and for fewer
+// classes, and for passphrases, if the password has enough words. Since
+// passwdqc also requires enough different characters, a password of this
+// length may still be rejected.
+//
+// It returns 0 if no length is sufficient for the current mix, that is,
+// all applicable Min values are Disabled, or if the password is empty,
+// contains NUL bytes, or is longer than MaxPasswordLength.
+func (p *Policy) RequiredLength(password []byte) int {
+	if statsError(password) != nil {
+		return 0
+	}
+	st := p.stats(password)
+	required := Disabled
+	for classes := st.classes; classes > 0; classes-- {
+		if n := p.MinForClasses(classes); n < required {
+			required = n
+		}
+		if n := p.MinForClasses(Passphrase); classes == 2 && p.PassphraseWords > 0 && st.words >= p.PassphraseWords && n < required {
+			required = n
+		}
+	}
+	if required == Disabled {
+		return 0
+	}
+	return required
+}
+
+// Satisfies checks the password with the user name, which may be nil,
+// like CheckAll and breaks the result into independent conditions, for
+// example, to show a checklist that is updated as the user types:
+//
+//   - lengthOK: the password is not empty and is not too short or too long
+//     (ErrEmpty, ErrNul, ErrShort, ErrLong, or ErrSimpleShort);
+//   - classesOK: the password has enough different characters and
+//     character classes for its length and the required characters
+//     (ErrSimple, ErrSimpleShort, ErrFewUnique, or ErrNoDigit and others);
+//   - notWord: it's not based on a dictionary word (ErrWord);
+//   - notSeq: it's not based on a common sequence (ErrSeq);
+//   - notPersonal: it's not based on the user name (ErrPersonal).
+//
+// Other checks, such as Wordlist, Blocklist, and DenyPatterns, are not
+// reported, so the password may be rejected even if all conditions are
+// true.
+func (p *Policy) Satisfies(password, username []byte) (lengthOK, classesOK, notWord, notSeq, notPersonal bool) {
+	lengthOK, classesOK, notWord, notSeq, notPersonal = true, true, true, true, true
+	for _, err := range p.CheckAll(password, nil, username) {
+		switch err {
+		case ErrEmpty, ErrNul:
+			lengthOK, classesOK = false, false
+		case ErrShort, ErrLong:
+			lengthOK = false
+		case ErrSimpleShort:
+			lengthOK, classesOK = false, false
+		case ErrSimple, ErrFewUnique, ErrNoDigit, ErrNoUpper, ErrNoLower, ErrNoSymbol:
+			classesOK = false
+		case ErrWord:
+			notWord = false
+		case ErrSeq:
+			notSeq = false
+		case ErrPersonal:
+			notPersonal = false
+		}
+	}
+	return
+}
+
+// EffectiveLength returns the length of the new password that passwdqc
+// compares with the Min values after taking into account its substrings
+// of at least MatchLength characters in common with the old password
+// (SimilarMatchLength, if set), if DenySimilar is set, the user name, and,
+// unless skipped because of SkipDictionary or HighEntropyBypass,
+// dictionary words and common sequences of characters. Depending on the
+// kind of the match, passwdqc either removes the substring and adds a
+// credit of MatchLength-1 characters, or discounts part of its length.
+// The shortest length resulting from any match is returned, so a long
+// password consisting mostly of a dictionary word or the user name may
+// have a much shorter effective length and be rejected as too simple.
+//
+// It returns the length of the password if nothing is discounted, and 0
+// for empty passwords, passwords containing NUL bytes or longer than
+// MaxPasswordLength, and if passwdqc fails, for example, because
+// MatchLength is negative. Passphrases are
+// compared with Min[2] without the discount for dictionary words.
+func (p *Policy) EffectiveLength(newPassword, oldPassword, username []byte) int {
+	if len(newPassword) == 0 || len(newPassword) > MaxPasswordLength || hasNul(newPassword) || hasNul(oldPassword) || hasNul(username) {
+		return 0
+	}
+	oldPassword, username = truncate(oldPassword), truncate(username)
+	params := p.params()
+	n := qcEffectiveLength(&params, newPassword, oldPassword, username, !p.skipDictionary(newPassword))
+	if n < 0 {
+		return 0 // passwdqc failed
+	}
+	return n
+}
+
+// StrengthCategory returns the strength of the password from 0 (very weak)
+// to 4 (very strong), suitable for strength meters, based on the number of
+// bits of randomness returned by Randomness:
+//
+//	0: less than 28 bits, or the password is empty or invalid
+//	1: 28 to 35 bits
+//	2: 36 to 59 bits
+//	3: 60 to 79 bits
+//	4: 80 bits or more
+//
+// The category doesn't depend on whether the password complies with the
+// policy: for example, a long password based on a dictionary word may be
+// rejected, but still get a high category, so meters should be shown
+// together with the result of Check.
+func (p *Policy) StrengthCategory(password []byte) int {
+	bits, err := p.Randomness(password)
+	if err != nil {
+		return 0
+	}
+	category := 0
+	for _, min := range strengthBits {
+		if bits >= min {
+			category++
+		}
+	}
+	return category
+}
+
+// strengthBits are the minimum numbers of bits for strength categories 1
+// to 4.
+var strengthBits = [...]float64{28, 36, 60, 80}
+
+// EstimateCrackTime returns the approximate time needed to guess the
+// password by trying guessesPerSecond guesses per second.
+//
+// The estimate assumes that the attacker needs to try half of all random
+// passwords with the same length and number of character classes, which
+// is the approximate entropy reported by CheckResult. Since passwords
+// chosen by people are often much easier to guess, the result is a rough
+// upper bound rather than a guarantee. Durations too large to represent,
+// or a guessesPerSecond that is not positive, result in math.MaxInt64.
+// Empty passwords, passwords containing NUL bytes, and passwords longer
+// than MaxPasswordLength result in 0.
+func (p *Policy) EstimateCrackTime(password []byte, guessesPerSecond float64) time.Duration {
+	if statsError(password) != nil {
+		return 0
+	}
+	st := passwordStats(password, 0, p.NonASCIIAsLetters)
+	bits := entropy(&st)
+	if bits == 0 {
+		return 0
+	}
+	if !(guessesPerSecond > 0) {
+		return math.MaxInt64
+	}
+	d := math.Exp2(bits-1) / guessesPerSecond * float64(time.Second)
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
+
+// charsetSizes are the sizes of character sets passwdqc assumes for
+// passwords with one to four character classes.
+var charsetSizes = [...]float64{10, 36, 62, 95}
+
+// stats returns passwdqc statistics for the password with the policy's
+// passphrase word length and classification of non-ASCII characters.
+func (p *Policy) stats(password []byte) qcStats {
+	return passwordStats(password, p.PassphraseMinWordLen, p.NonASCIIAsLetters)
+}
+
+// entropy returns the entropy in bits of a random password with the
+// same length and number of character classes.
+//
+// This is a rough upper bound: passwords chosen by people usually have
+// much less entropy than random ones.
+func entropy(st *qcStats) float64 {
+	if st.length == 0 || st.classes == 0 {
+		return 0
+	}
+	n := st.classes
+	if n > len(charsetSizes) {
+		n = len(charsetSizes)
+	}
+	return float64(st.length) * math.Log2(charsetSizes[n-1])
+}
@@ -0,0 +1,41 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import "sync/atomic"
+
+// Stats counts the outcomes of checks by reason, with ReasonNone counting
+// accepted passwords. It's updated with atomic operations and doesn't
+// allocate, so it can be used with policies checking many passwords
+// concurrently. The zero value is ready to use.
+type Stats struct {
+	counts [len(reasonNames)]atomic.Uint64
+}
+
+// add counts a check with the reason.
+func (s *Stats) add(reason Reason) {
+	if reason >= 0 && int(reason) < len(s.counts) {
+		s.counts[reason].Add(1)
+	}
+}
+
+// Snapshot returns the current counts for reasons that occurred at least
+// once. Since counters are read one by one, checks performed concurrently
+// with Snapshot may be counted only for some reasons.
+func (s *Stats) Snapshot() map[Reason]uint64 {
+	m := make(map[Reason]uint64)
+	for i := range s.counts {
+		if n := s.counts[i].Load(); n > 0 {
+			m[Reason(i)] = n
+		}
+	}
+	return m
+}
+
+// Reset sets all counts to zero.
+func (s *Stats) Reset() {
+	for i := range s.counts {
+		s.counts[i].Store(0)
+	}
+}
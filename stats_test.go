@@ -0,0 +1,42 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.Stats = new(Stats)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pol.CheckNew([]byte("dw1lIojbTBrq/gii"))
+			pol.CheckNew([]byte("sh0rt"))
+			pol.CheckNew(nil)
+		}()
+	}
+	wg.Wait()
+	expected := map[Reason]uint64{ReasonNone: 10, ReasonShort: 10, ReasonEmpty: 10}
+	if s := pol.Stats.Snapshot(); !reflect.DeepEqual(s, expected) {
+		t.Errorf("expected %v, got %v", expected, s)
+	}
+	pol.Stats.Reset()
+	if s := pol.Stats.Snapshot(); len(s) != 0 {
+		t.Errorf("expected no counts after Reset, got %v", s)
+	}
+}
+
+func TestStatsAllocs(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.Stats = new(Stats)
+	if n := testing.AllocsPerRun(100, func() { pol.Stats.add(ReasonShort) }); n != 0 {
+		t.Errorf("expected no allocations, got %v", n)
+	}
+}
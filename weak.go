@@ -0,0 +1,128 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// WeakSubstrings returns the substrings of the password at least
+// MatchLength characters long that passwdqc finds in its dictionary words
+// or common sequences of characters, such as "dragon" in "Dragon!2x" or
+// "4321" in "a4321b", in the order of their positions in the password,
+// for giving feedback to users. Substrings contained in a longer returned
+// one are omitted, and substrings found only reversed, such as "nogard",
+// are returned as they appear in the password.
+//
+// As in passwdqc, substrings of dictionary words containing characters
+// other than letters, such as leetspeak, must be one character longer to
+// be found. A found substring doesn't mean that the password is rejected:
+// passwdqc only rejects passwords that are too simple without it.
+//
+// It returns nil for empty passwords, passwords containing NUL bytes or
+// longer than MaxPasswordLength, if MatchLength is 0, or if dictionary
+// checks are skipped because of SkipDictionary or HighEntropyBypass.
+func (p *Policy) WeakSubstrings(password []byte) []string {
+	if len(password) == 0 || len(password) > MaxPasswordLength || hasNul(password) || p.MatchLength <= 0 || p.skipDictionary(password) {
+		return nil
+	}
+	params := p.params()
+	n := int(params.matchLength)
+	index := weakIndex(&params)
+	pw := string(password)
+	needle := params.unify(pw)
+	// end[i] is the end of the longest weak substring starting at i.
+	end := make([]int, len(needle))
+	for i := 0; i+n <= len(needle); i++ {
+		for _, h := range index[needle[i:i+n]] {
+			j := i + n + 1
+			for j <= len(needle) && strings.Contains(h.s, needle[i:j]) {
+				j++
+			}
+			j--
+			if j == i+n && h.word && !allAlpha(pw[i:j]) {
+				continue
+			}
+			if j > end[i] {
+				end[i] = j
+			}
+		}
+	}
+	var found []string
+	last := 0
+	for i, j := range end {
+		if j > last {
+			found = append(found, pw[i:j])
+			last = j
+		}
+	}
+	return found
+}
+
+// allAlpha reports whether s consists of ASCII letters only.
+func allAlpha(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isAlpha(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// weakHaystack is a unified dictionary word or sequence, or its reverse,
+// searched by WeakSubstrings.
+type weakHaystack struct {
+	s    string
+	word bool // from wordset4k
+}
+
+type weakIndexKey struct {
+	unifyMap    [0x100]byte
+	matchLength int32
+}
+
+// weakIndexes caches indexes built by weakIndex by weakIndexKey.
+var weakIndexes sync.Map
+
+// weakIndex returns a map from each substring of matchLength characters of
+// the haystacks searched by WeakSubstrings to the haystacks containing it,
+// so that only those are searched at each position of the password.
+func weakIndex(params *qcParams) map[string][]weakHaystack {
+	key := weakIndexKey{params.unifyMap, params.matchLength}
+	if index, ok := weakIndexes.Load(key); ok {
+		return index.(map[string][]weakHaystack)
+	}
+	n := int(params.matchLength)
+	index := make(map[string][]weakHaystack)
+	add := func(haystack string, word bool) {
+		for _, h := range [...]string{haystack, reverse(haystack)} {
+			seen := make(map[string]bool)
+			for i := 0; i+n <= len(h); i++ {
+				gram := h[i : i+n]
+				if !seen[gram] {
+					seen[gram] = true
+					index[gram] = append(index[gram], weakHaystack{h, word})
+				}
+			}
+		}
+	}
+	for i, word := range wordset4k {
+		if len(word) < n || i < 0xfff && strings.HasPrefix(wordset4k[i+1], word) {
+			continue
+		}
+		add(params.unify(word), true)
+	}
+	for _, s := range seq {
+		add(params.unify(s), false)
+	}
+	if n <= 4 {
+		for i := 1900; i <= 2039; i++ {
+			add(strconv.Itoa(i), false)
+		}
+	}
+	actual, _ := weakIndexes.LoadOrStore(key, index)
+	return actual.(map[string][]weakHaystack)
+}
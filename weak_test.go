@@ -0,0 +1,45 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestWeakSubstrings(t *testing.T) {
+	tests := []struct {
+		password string
+		want     []string
+	}{
+		{"Dragon!2x", []string{"Dragon"}},
+		{"xNOGARDx", []string{"NOGARD"}},
+		{"dr4gon99", []string{"dr4gon"}},
+		{"a4321b", []string{"4321"}},
+		{"qwerty1985Zz", []string{"qwerty", "1985"}},
+		{"applemonkey", []string{"apple", "lemon", "monkey"}},
+		{"dw1lIojbTBrq/gii1MzfZVL83wlIdAe/2v1xsQmybHU", nil},
+		{"", nil},
+		{"dragon\x00", nil},
+	}
+	for _, v := range tests {
+		if got := DefaultPolicy.WeakSubstrings([]byte(v.password)); !reflect.DeepEqual(got, v.want) {
+			t.Errorf("%q: expected %q, got %q", v.password, v.want, got)
+		}
+	}
+	pol := *DefaultPolicy
+	pol.SkipDictionary = true
+	if got := pol.WeakSubstrings([]byte("Dragon!2x")); got != nil {
+		t.Errorf("expected nil with SkipDictionary, got %q", got)
+	}
+	long := bytes.Repeat([]byte("x"), MaxPasswordLength)
+	copy(long[MaxPasswordLength-6:], "dragon")
+	if got := DefaultPolicy.WeakSubstrings(long); !reflect.DeepEqual(got, []string{"dragon"}) {
+		t.Errorf("expected %q at maximum length, got %q", "dragon", got)
+	}
+	if got := DefaultPolicy.WeakSubstrings(append(long, 'x')); got != nil {
+		t.Errorf("expected nil for password over MaxPasswordLength, got %q", got)
+	}
+}
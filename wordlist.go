@@ -0,0 +1,198 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Wordlist is a set of words, such as common or breached passwords, which
+// passwords must not be based on.
+//
+// Words are stored in a sorted slice of substrings of a single string, so
+// a wordlist takes about 16 bytes of memory per word in addition to the
+// length of the words.
+type Wordlist struct {
+	words  []string // sorted and unique lower-case words
+	maxLen int      // length of the longest word
+}
+
+// NewWordlist returns a new wordlist with the given words. Letter case of
+// words is ignored, and empty words are skipped.
+func NewWordlist(words []string) *Wordlist {
+	lower := make([]string, 0, len(words))
+	n := 0
+	for _, w := range words {
+		if w = strings.ToLower(w); w != "" {
+			lower = append(lower, w)
+			n += len(w)
+		}
+	}
+	// Store all words in a single string.
+	var b strings.Builder
+	b.Grow(n)
+	for _, w := range lower {
+		b.WriteString(w)
+	}
+	all := b.String()
+	wl := new(Wordlist)
+	wl.words = make([]string, len(lower))
+	for i, w := range lower {
+		wl.words[i], all = all[:len(w)], all[len(w):]
+		if len(w) > wl.maxLen {
+			wl.maxLen = len(w)
+		}
+	}
+	sort.Strings(wl.words)
+	// Remove duplicates.
+	j := 0
+	for i, w := range wl.words {
+		if i == 0 || w != wl.words[j-1] {
+			wl.words[j] = w
+			j++
+		}
+	}
+	wl.words = wl.words[:j]
+	return wl
+}
+
+// LoadWordlistGzip reads a gzip-compressed file with one word per line,
+// such as testdata/passwords.txt.gz, and returns the words. Empty lines are
+// skipped. The words can be passed to NewWordlist.
+func LoadWordlistGzip(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	z, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	return readWords(z)
+}
+
+// loadWordlistFile reads a file with one word per line, which is
+// gzip-compressed if the file name ends with ".gz", and returns the words.
+func loadWordlistFile(filename string) ([]string, error) {
+	if strings.HasSuffix(filename, ".gz") {
+		return LoadWordlistGzip(filename)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readWords(f)
+}
+
+// readWords returns words read from r, one per line, skipping empty lines.
+func readWords(r io.Reader) ([]string, error) {
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if w := strings.TrimSuffix(scanner.Text(), "\r"); w != "" {
+			words = append(words, w)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// Len returns the number of words in the wordlist.
+func (wl *Wordlist) Len() int {
+	return len(wl.words)
+}
+
+// Contains reports whether the wordlist contains the word, ignoring case.
+func (wl *Wordlist) Contains(word []byte) bool {
+	return wl.contains(string(bytes.ToLower(word)))
+}
+
+func (wl *Wordlist) contains(w string) bool {
+	i := sort.SearchStrings(wl.words, w)
+	return i < len(wl.words) && wl.words[i] == w
+}
+
+// substrings returns words from the wordlist at least minLen bytes long
+// that the password contains, ignoring case. If the deadline is exceeded,
+// it returns the words found so far.
+func (wl *Wordlist) substrings(password []byte, minLen int, d *deadline) []string {
+	if minLen < 1 {
+		minLen = 1
+	}
+	s := string(bytes.ToLower(password))
+	var found []string
+	for i := range s {
+		if d.exceeded() {
+			break
+		}
+		for j := i + minLen; j <= len(s) && j-i <= wl.maxLen; j++ {
+			if wl.contains(s[i:j]) {
+				found = append(found, s[i:j])
+			}
+		}
+	}
+	return found
+}
+
+// basedOnWordlist reports whether the password is based on a word from the
+// wordlist, that is, contains a word at least MatchLength characters long
+// and would be too simple without it, as passwdqc determines for the user
+// name. Scanning stops when the deadline is exceeded.
+func (p *Policy) basedOnWordlist(wl *Wordlist, password []byte, d *deadline) bool {
+	if p.MatchLength == 0 {
+		return false
+	}
+	found := wl.substrings(password, p.MatchLength, d)
+	if len(found) == 0 {
+		return false
+	}
+	params := p.params()
+	for _, w := range found {
+		if d.exceeded() {
+			return false
+		}
+		if qcBasedOn(&params, password, []byte(w)) {
+			return true
+		}
+	}
+	return false
+}
+
+// deadline limits the time spent scanning wordlists (see MaxCheckDuration).
+// A nil deadline never expires.
+type deadline struct {
+	t       time.Time
+	expired bool
+}
+
+// newDeadline returns a deadline d from now, or nil if d is not positive.
+func newDeadline(d time.Duration) *deadline {
+	if d <= 0 {
+		return nil
+	}
+	return &deadline{t: time.Now().Add(d)}
+}
+
+// exceeded reports whether the deadline has passed.
+func (d *deadline) exceeded() bool {
+	if d == nil {
+		return false
+	}
+	if !d.expired && time.Now().After(d.t) {
+		d.expired = true
+	}
+	return d.expired
+}
@@ -0,0 +1,133 @@
+// Go code written in 2014 by Dmitry Chestnykh.
+// See LICENSE file.
+
+package passwordcheck
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWordlist(t *testing.T) {
+	wl := NewWordlist([]string{"Dragon", "dragon", "", "monkey", "letmein"})
+	if wl.Len() != 3 {
+		t.Errorf("expected 3 words, got %d", wl.Len())
+	}
+	if !wl.Contains([]byte("DRAGON")) || wl.Contains([]byte("drago")) {
+		t.Error("incorrect Contains result")
+	}
+	found := wl.substrings([]byte("xLetMeIn!dragon"), 4, nil)
+	if len(found) != 2 || found[0] != "letmein" || found[1] != "dragon" {
+		t.Errorf("unexpected substrings %q", found)
+	}
+}
+
+func TestLoadWordlistGzip(t *testing.T) {
+	words, err := LoadWordlistGzip("testdata/passwords.txt.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(words) < 10000 {
+		t.Fatalf("expected at least 10000 words, got %d", len(words))
+	}
+	pol := *DefaultPolicy
+	pass := []byte("Ncc1701!basketball")
+	if err := pol.Check(pass, nil, nil); err != nil {
+		t.Fatalf("no error expected without wordlist, got %v", err)
+	}
+	pol.Wordlist = NewWordlist(words)
+	if err := pol.Check(pass, nil, nil); err != ErrWord {
+		t.Errorf("expected ErrWord, got %v", err)
+	}
+	// Passwords strong enough without the word are accepted.
+	pass = []byte("dw1lIojbTBrq/gii1MzfZVL83wlIdAe/basketball")
+	if err := pol.Check(pass, nil, nil); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+	if _, err := LoadWordlistGzip("testdata/README"); err == nil {
+		t.Error("expected error for non-gzip file")
+	}
+}
+
+func TestDictionaryFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(filename, []byte("ncc1701\r\n\nbasketball\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pass := []byte("Ncc1701!basketball")
+	for _, name := range []string{filename, "testdata/passwords.txt.gz"} {
+		pol, err := NewPolicy("dictfile=" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := pol.Check(pass, nil, nil); err != ErrWord {
+			t.Errorf("%s: expected ErrWord, got %v", name, err)
+		}
+	}
+	pol := *DefaultPolicy
+	pol.DictionaryFile = filename
+	if err := pol.Check(pass, nil, nil); err != ErrFailed {
+		t.Errorf("expected ErrFailed before loading, got %v", err)
+	}
+	if err := pol.LoadDictionaryFile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pol.Check(pass, nil, nil); err != ErrWord {
+		t.Errorf("expected ErrWord, got %v", err)
+	}
+	if err := pol.Check([]byte("Mooseaxon#7Tq"), nil, nil); err != nil {
+		t.Errorf("no error expected, got %v", err)
+	}
+	missing := filepath.Join(t.TempDir(), "missing")
+	if _, err := NewPolicy("dictfile=" + missing); err == nil || !strings.Contains(err.Error(), "error reading dictionary file") {
+		t.Errorf("expected error reading dictionary file, got %v", err)
+	}
+	// ParsePolicy and GobDecode don't read files.
+	parsed, err := ParsePolicy("dictfile=" + missing)
+	if err != nil {
+		t.Fatalf("ParsePolicy: unexpected error %v", err)
+	}
+	var decoded Policy
+	if err := decoded.GobDecode([]byte(parsed.String())); err != nil {
+		t.Fatalf("GobDecode: unexpected error %v", err)
+	}
+	if decoded.DictionaryFile != missing {
+		t.Errorf("expected DictionaryFile %q, got %q", missing, decoded.DictionaryFile)
+	}
+	if err := decoded.Check(pass, nil, nil); err != ErrFailed {
+		t.Errorf("expected ErrFailed before loading, got %v", err)
+	}
+	t.Setenv("DICTTEST_DICTFILE", filename)
+	envPol, err := PolicyFromEnv("DICTTEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := envPol.Check(pass, nil, nil); err != ErrWord {
+		t.Errorf("PolicyFromEnv: expected ErrWord, got %v", err)
+	}
+	pol.DictionaryFile = missing
+	if err := pol.LoadDictionaryFile(); !os.IsNotExist(errors.Unwrap(err)) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+}
+
+func TestMaxCheckDuration(t *testing.T) {
+	pol := *DefaultPolicy
+	pol.Wordlist = NewWordlist([]string{"ncc1701", "basketball"})
+	pol.MaxCheckDuration = time.Hour
+	pass := []byte("Ncc1701!basketball")
+	if err := pol.Check(pass, nil, nil); err != ErrWord {
+		t.Errorf("expected ErrWord within the time limit, got %v", err)
+	}
+	expired := &deadline{t: time.Now().Add(-time.Second)}
+	if pol.basedOnWordlist(pol.Wordlist, pass, expired) || !expired.exceeded() {
+		t.Error("expected scanning to stop after the deadline")
+	}
+	if newDeadline(0) != nil || newDeadline(0).exceeded() {
+		t.Error("expected no deadline without MaxCheckDuration")
+	}
+}